@@ -0,0 +1,194 @@
+// Hoconfmt formats HOCON config files.
+//
+// Without an explicit path, it processes the standard input. Given a file,
+// it operates on that file; given a directory, it operates on all .conf
+// files in that directory, recursively. By default, hoconfmt prints the
+// formatted sources to standard output.
+//
+// Usage:
+//
+//	hoconfmt [flags] [path ...]
+//
+// The flags are:
+//
+//	-l      list files whose formatting differs from hoconfmt's
+//	-d      display diffs instead of rewriting files
+//	-w      write result to (source) file instead of stdout
+//	-check  load files with their includes and report errors,
+//	        without formatting
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/tbud/x/config"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	list  = flag.Bool("l", false, "list files whose formatting differs from hoconfmt's")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	check = flag.Bool("check", false, "load files with their includes and report errors")
+)
+
+var exitCode = 0
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: hoconfmt [flags] [path ...]")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write || *check {
+			fmt.Fprintln(os.Stderr, "error: cannot use -w or -check with standard input")
+			os.Exit(2)
+		}
+		if err := processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	}
+
+	for _, path := range flag.Args() {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			report(err)
+		case info.IsDir():
+			walkDir(path)
+		default:
+			if err := processPath(path); err != nil {
+				report(err)
+			}
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func walkDir(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(info.Name(), ".conf") && !strings.HasPrefix(info.Name(), ".") {
+			err = processPath(path)
+		}
+		if err != nil {
+			report(err)
+		}
+		return nil
+	})
+}
+
+func processPath(path string) error {
+	if *check {
+		_, err := config.Load(path)
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return processFile(path, f, os.Stdout)
+}
+
+// processFile formats the source of in, and writes the result to out or
+// back to the file of name, as the flags say.
+func processFile(name string, in io.Reader, out io.Writer) error {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	res, err := config.FormatSource(src)
+	if err != nil {
+		if synErr, ok := err.(*config.SyntaxError); ok && len(synErr.File) == 0 {
+			return fmt.Errorf("%s:%v", name, err)
+		}
+		return err
+	}
+
+	if bytes.Equal(src, res) {
+		if !*list && !*diff && !*write {
+			_, err = out.Write(res)
+		}
+		return err
+	}
+
+	if *list {
+		fmt.Fprintln(out, name)
+	}
+	if *write {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(name, res, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *diff {
+		d, err := diffSource(name, src, res)
+		if err != nil {
+			return fmt.Errorf("computing diff: %s", err)
+		}
+		out.Write(d)
+	}
+	if !*list && !*write && !*diff {
+		_, err = out.Write(res)
+	}
+	return err
+}
+
+// diffSource returns the unified diff of the source b1 and the formatted
+// source b2 of file name, by the diff command.
+func diffSource(name string, b1, b2 []byte) ([]byte, error) {
+	f1, err := writeTempFile("hoconfmt", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f1)
+
+	f2, err := writeTempFile("hoconfmt", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f2)
+
+	data, err := exec.Command("diff", "-u", "--label", name+".orig", "--label", name, f1, f2).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files don't match
+		return data, nil
+	}
+	return data, err
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	file, err := os.CreateTemp("", prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProcessFile(t *testing.T) {
+	src := "a {\n  b : 1\n}\n"
+	want := "a {\n\tb = 1\n}\n"
+
+	var out bytes.Buffer
+	if err := processFile("a.conf", strings.NewReader(src), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("want %q, get %q", want, out.String())
+	}
+
+	*list = true
+	defer func() { *list = false }()
+
+	out.Reset()
+	if err := processFile("a.conf", strings.NewReader(src), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "a.conf\n" {
+		t.Errorf("want a.conf listed, get %q", out.String())
+	}
+
+	out.Reset()
+	if err := processFile("b.conf", strings.NewReader(want), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() > 0 {
+		t.Errorf("want formatted file not listed, get %q", out.String())
+	}
+
+	err := processFile("c.conf", strings.NewReader("a = 1\n}\n"), &out)
+	if err == nil || !strings.HasPrefix(err.Error(), "c.conf:2:") {
+		t.Errorf("want syntax error of c.conf, get %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"io"
+	"runtime"
+	"strings"
+)
+
+// A NodeKind is the kind of a Node.
+type NodeKind int
+
+const (
+	FieldNode   NodeKind = iota // a key with its value, such as a.b = 1 or a { ... }
+	CommentNode                 // a # comment
+	IncludeNode                 // an include statement
+)
+
+// A Node is a node of the syntax tree of a HOCON document, see Parse.
+type Node struct {
+	Kind NodeKind
+
+	// Key is the path of a field relative to its object, such as a.b,
+	// with keys quoted if needed, see ParsePath.
+	Key string
+
+	// Value is the source text of the value of a field, such as 30s,
+	// "text", ${base} or [1, 2], and is empty for a field whose value is
+	// an object only. It is the text of a comment, without '#', and the
+	// argument of an include, such as required("a.conf").
+	Value string
+
+	// Append is true for a field of +=.
+	Append bool
+
+	// Object is the fields, comments and includes of the object value of
+	// a field, nil if the value is not an object.
+	Object []*Node
+
+	Line   int // line of the node, starting at 1
+	Column int // column of the node, starting at 1
+}
+
+// Parse parses the HOCON document of reader to its syntax tree, with the
+// same parser as Load and Read. Includes are not loaded, and substitutions
+// are not resolved. Errors are *SyntaxError with the position of the error.
+func Parse(reader io.Reader) (nodes []*Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	scan := fileScanner{tree: &treeBuilder{}}
+	if err = scan.checkReaderValid(reader); err != nil {
+		return nil, err
+	}
+	return scan.tree.nodes, nil
+}
+
+// A treeBuilder builds the syntax tree of a document while scanning.
+type treeBuilder struct {
+	nodes  []*Node
+	stack  []*Node // open objects, nil for the braces of the root object
+	last   *Node   // the last field
+	concat bool    // an object follows the last field, such as ${base} { ... }
+}
+
+func (t *treeBuilder) add(node *Node) {
+	if len(t.stack) > 0 && t.stack[len(t.stack)-1] != nil {
+		top := t.stack[len(t.stack)-1]
+		top.Object = append(top.Object, node)
+	} else {
+		t.nodes = append(t.nodes, node)
+	}
+	if node.Kind == FieldNode {
+		t.last = node
+	}
+	t.concat = false
+}
+
+// field adds a field of key with the source text value.
+func (t *treeBuilder) field(key, value string, appending bool, pos position) *Node {
+	node := &Node{Kind: FieldNode, Key: key, Value: value, Append: appending, Line: pos.line, Column: pos.column}
+	t.add(node)
+	return node
+}
+
+// concatValue sets the value of the last field to the value concatenated
+// to its object or array, such as { x = 1 } ${b}. objectRaw is the source
+// text of the object, empty for an array.
+func (t *treeBuilder) concatValue(objectRaw, value string) {
+	if t.last == nil {
+		return
+	}
+	if len(objectRaw) > 0 {
+		t.last.Value, t.last.Object = objectRaw, nil
+	}
+	t.last.Value += " " + value
+}
+
+// open opens the object value of field key, an empty key opens the braces
+// of the root object.
+func (t *treeBuilder) open(key string, pos position) {
+	if len(key) == 0 && len(t.stack) == 0 {
+		t.stack = append(t.stack, nil)
+		return
+	}
+
+	node := t.last
+	if !t.concat || node == nil || node.Key != key {
+		node = t.field(key, "", false, pos)
+	}
+	if node.Object == nil {
+		node.Object = []*Node{}
+	}
+	t.stack = append(t.stack, node)
+	t.concat = false
+}
+
+func (t *treeBuilder) close() {
+	if len(t.stack) > 0 {
+		if top := t.stack[len(t.stack)-1]; top != nil {
+			t.last = top
+		}
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+}
+
+// fieldKey returns the key of the current field relative to its object.
+func (s *fileScanner) fieldKey() string {
+	base := 0
+	if stackLen := len(s.keyStack); stackLen > 0 {
+		base = s.keyStack[stackLen-1]
+	}
+
+	key := ""
+	for _, k := range s.baseKeys[base:] {
+		key = joinKey(key, k)
+	}
+	return key
+}
+
+// rawValue returns the source text of the current value.
+func (s *fileScanner) rawValue() string {
+	return strings.TrimSpace(string(s.data[s.valueStart:s.bytes]))
+}
@@ -2,68 +2,29 @@ package config
 
 import (
 	"errors"
-	"io"
-	"path/filepath"
+	"fmt"
 	"reflect"
-	"runtime"
-	"strings"
+	"strconv"
+	"time"
 	"unicode"
-)
-
-type Config map[string]interface{}
-
-func Load(fileName string) (config Config, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
-			}
-			err = r.(error)
-		}
-	}()
-
-	config = Config{}
-	scan := fileScanner{}
-
-	if !filepath.IsAbs(fileName) {
-		fileName, err = filepath.Abs(fileName)
-		if err != nil {
-			return
-		}
-	}
-
-	err = scan.checkValid(fileName)
-	if err != nil {
-		return
-	}
-
-	err = scan.setOptions(config)
-	return
-}
-
-func Read(reader io.Reader) (config Config, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
-			}
-			err = r.(error)
-		}
-	}()
-
-	config = Config{}
-	scan := fileScanner{}
 
-	err = scan.checkReaderValid(reader)
-	if err != nil {
-		return
-	}
+	"github.com/tbud/x/container/linkedmap"
+)
 
-	err = scan.setOptions(config)
-	return
+// A Config is an object of a config file. The values of its objects are
+// *Config, the values of its arrays are []interface{}. The keys are kept
+// in the order they are declared, see Keys. A nil *Config has no keys.
+//
+// Config was a map[string]interface{}, which loses the order of the keys.
+// Code which built a Config as a map or ranged over it converts with
+// FromMap and Map, and appender.AppenderMaker and layout.LayoutMaker take a
+// *Config.
+type Config struct {
+	values   *linkedmap.LinkedMap   // values of keys, in the order they are set
+	comments map[string]*keyComment // comments of keys read from a file
 }
 
-func (c Config) Int(key string) (result int, found bool) {
+func (c *Config) Int(key string) (result int, found bool) {
 	result, found = 0, false
 	value := c.getValue(key)
 	if value == nil {
@@ -77,7 +38,7 @@ func (c Config) Int(key string) (result int, found bool) {
 	return
 }
 
-func (c Config) IntDefault(key string, defaultValue int) int {
+func (c *Config) IntDefault(key string, defaultValue int) int {
 	result, found := c.Int(key)
 	if !found {
 		result = defaultValue
@@ -85,7 +46,7 @@ func (c Config) IntDefault(key string, defaultValue int) int {
 	return result
 }
 
-func (c Config) Float(key string) (result float64, found bool) {
+func (c *Config) Float(key string) (result float64, found bool) {
 	result, found = 0.0, false
 	value := c.getValue(key)
 	if value == nil {
@@ -96,7 +57,7 @@ func (c Config) Float(key string) (result float64, found bool) {
 	return
 }
 
-func (c Config) FloatDefault(key string, defaultValue float64) float64 {
+func (c *Config) FloatDefault(key string, defaultValue float64) float64 {
 	result, found := c.Float(key)
 	if !found {
 		result = defaultValue
@@ -104,7 +65,7 @@ func (c Config) FloatDefault(key string, defaultValue float64) float64 {
 	return result
 }
 
-func (c Config) String(key string) (result string, found bool) {
+func (c *Config) String(key string) (result string, found bool) {
 	result, found = "", false
 	value := c.getValue(key)
 	if value == nil {
@@ -115,7 +76,7 @@ func (c Config) String(key string) (result string, found bool) {
 	return
 }
 
-func (c Config) StringDefault(key, defaultValue string) string {
+func (c *Config) StringDefault(key, defaultValue string) string {
 	result, found := c.String(key)
 	if !found {
 		result = defaultValue
@@ -123,7 +84,7 @@ func (c Config) StringDefault(key, defaultValue string) string {
 	return result
 }
 
-func (c Config) Bool(key string) (result, found bool) {
+func (c *Config) Bool(key string) (result, found bool) {
 	result, found = false, false
 	value := c.getValue(key)
 	if value == nil {
@@ -134,7 +95,7 @@ func (c Config) Bool(key string) (result, found bool) {
 	return
 }
 
-func (c Config) BoolDefault(key string, defaultValue bool) bool {
+func (c *Config) BoolDefault(key string, defaultValue bool) bool {
 	result, found := c.Bool(key)
 	if !found {
 		result = defaultValue
@@ -142,28 +103,90 @@ func (c Config) BoolDefault(key string, defaultValue bool) bool {
 	return result
 }
 
-func (c Config) Strings(key string) (result []string, found bool) {
-	result, found = []string{}, false
+// Duration returns the duration of key. A string value is a number with
+// an optional unit, such as "30s", "100 ms" or "2h", a number value is
+// in milliseconds. Units are ns, us, ms, s, m, h and d.
+func (c *Config) Duration(key string) (result time.Duration, found bool) {
 	value := c.getValue(key)
 	if value == nil {
-		return
+		return 0, false
 	}
 
-	if infs, f := value.([]interface{}); f {
-		for _, inf := range infs {
-			if v, ok := inf.(string); ok {
-				found = true
-				result = append(result, v)
-			}
-		}
-		return
+	result, err := toDuration(value)
+	return result, err == nil
+}
+
+func (c *Config) DurationDefault(key string, defaultValue time.Duration) time.Duration {
+	result, found := c.Duration(key)
+	if !found {
+		result = defaultValue
 	}
+	return result
+}
 
-	result, found = value.([]string)
+// Bytes returns the size in bytes of key. A string value is a number
+// with an optional unit, such as "10MB" or "512K", a number value is in
+// bytes. kB, MB, GB ... are powers of ten, and K, KiB, M, MiB, G, GiB ...
+// are powers of two.
+func (c *Config) Bytes(key string) (result int64, found bool) {
+	value := c.getValue(key)
+	if value == nil {
+		return 0, false
+	}
+
+	result, err := toBytes(value)
+	return result, err == nil
+}
+
+func (c *Config) BytesDefault(key string, defaultValue int64) int64 {
+	result, found := c.Bytes(key)
+	if !found {
+		result = defaultValue
+	}
+	return result
+}
+
+// Slice stores the array of key in the slice pointed to by v, such as
+// *[]int or *[]time.Duration, with the conversions of Unmarshal. An element
+// which can not be converted is reported by an *UnmarshalTypeError with the
+// key of the element, such as "servers.1". An object with numeric keys, such
+// as the properties foo.0 and foo.1, is converted to an array.
+func (c *Config) Slice(key string, v interface{}) error {
+	value, found := c.lookup(key)
+	return sliceValue(key, value, found, v)
+}
+
+// sliceValue stores the array value of key in the slice pointed to by v.
+func sliceValue(key string, value interface{}, found bool, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("config: Slice(" + fmt.Sprintf("%T", v) + "), v must be a non-nil pointer to slice")
+	}
+
+	if !found || value == nil {
+		return errors.New("config: key " + strconv.Quote(key) + " not found")
+	}
+
+	if err := unmarshalValue(key, value, rv.Elem()); err != nil {
+		rv.Elem().Set(reflect.MakeSlice(rv.Elem().Type(), 0, 0))
+		return err
+	}
+	return nil
+}
+
+// Strings returns the strings of the array of key, numbers and bools are
+// converted to strings. found is false if key is not set, or if it is not
+// an array or has an element which can not be converted, such as an
+// object. Slice reports the element which can not be converted.
+func (c *Config) Strings(key string) (result []string, found bool) {
+	found = c.Slice(key, &result) == nil
+	if result == nil {
+		result = []string{}
+	}
 	return
 }
 
-func (c Config) StringsDefault(key string, defaultValue []string) []string {
+func (c *Config) StringsDefault(key string, defaultValue []string) []string {
 	result, found := c.Strings(key)
 	if !found {
 		result = defaultValue
@@ -171,57 +194,142 @@ func (c Config) StringsDefault(key string, defaultValue []string) []string {
 	return result
 }
 
-func (c Config) Bools(key string) (result []bool, found bool) {
-	result, found = []bool{}, false
-	value := c.getValue(key)
-	if value == nil {
-		return
+// Bools returns the bools of the array of key, strings such as "true" are
+// converted to bools. found is false if key is not set, or if it is not an
+// array or has an element which can not be converted, see Strings.
+func (c *Config) Bools(key string) (result []bool, found bool) {
+	found = c.Slice(key, &result) == nil
+	if result == nil {
+		result = []bool{}
 	}
+	return
+}
 
-	if infs, f := value.([]interface{}); f {
-		for _, inf := range infs {
-			if v, ok := inf.(bool); ok {
-				found = true
-				result = append(result, v)
-			}
-		}
-		return
+func (c *Config) BoolsDefault(key string, defaultValue []bool) []bool {
+	result, found := c.Bools(key)
+	if !found {
+		result = defaultValue
+	}
+	return result
+}
+
+// Ints returns the ints of the array of key, numeric strings such as "80"
+// are converted to ints. found is false if key is not set, or if it is not
+// an array or has an element which is not an integer, see Strings.
+func (c *Config) Ints(key string) (result []int, found bool) {
+	found = c.Slice(key, &result) == nil
+	if result == nil {
+		result = []int{}
+	}
+	return
+}
+
+func (c *Config) IntsDefault(key string, defaultValue []int) []int {
+	result, found := c.Ints(key)
+	if !found {
+		result = defaultValue
 	}
+	return result
+}
 
-	result, found = value.([]bool)
+// Floats returns the numbers of the array of key, numeric strings are
+// converted to numbers. found is false if key is not set, or if it is not
+// an array or has an element which is not a number, see Strings.
+func (c *Config) Floats(key string) (result []float64, found bool) {
+	found = c.Slice(key, &result) == nil
+	if result == nil {
+		result = []float64{}
+	}
 	return
 }
 
-func (c Config) BoolsDefault(key string, defaultValue []bool) []bool {
-	result, found := c.Bools(key)
+func (c *Config) FloatsDefault(key string, defaultValue []float64) []float64 {
+	result, found := c.Floats(key)
 	if !found {
 		result = defaultValue
 	}
 	return result
 }
 
-func subConfig(value interface{}) Config {
-	if value != nil {
-		if v, ok := value.(map[string]interface{}); ok {
-			return Config(v)
-		}
-		if v, ok := value.(Config); ok {
-			return v
+// Durations returns the durations of the array of key, such as
+// ["1s", "500ms"], numbers are in milliseconds. found is false if key is
+// not set, or if it is not an array or has an element which is not a
+// duration, see Strings.
+func (c *Config) Durations(key string) (result []time.Duration, found bool) {
+	found = c.Slice(key, &result) == nil
+	if result == nil {
+		result = []time.Duration{}
+	}
+	return
+}
+
+func (c *Config) DurationsDefault(key string, defaultValue []time.Duration) []time.Duration {
+	result, found := c.Durations(key)
+	if !found {
+		result = defaultValue
+	}
+	return result
+}
+
+// SubConfigs returns the objects of the array of key, such as
+// servers = [{host = a}, {host = b}]. found is false if any element
+// is not an object.
+func (c *Config) SubConfigs(key string) (result []*Config, found bool) {
+	result = []*Config{}
+	value := c.getValue(key)
+	elems, ok := value.([]interface{})
+	if object := subConfig(value); object != nil {
+		var err error
+		elems, err = numericArray(object)
+		ok = err == nil
+	}
+	if !ok {
+		return
+	}
+
+	for _, elem := range elems {
+		conf := subConfig(elem)
+		if conf == nil {
+			return []*Config{}, false
 		}
+		result = append(result, conf)
+	}
+	return result, true
+}
+
+func (c *Config) SubConfigsDefault(key string, defaultValue []*Config) []*Config {
+	result, found := c.SubConfigs(key)
+	if !found {
+		result = defaultValue
+	}
+	return result
+}
+
+func subConfig(value interface{}) *Config {
+	if v, ok := value.(*Config); ok {
+		return v
 	}
 	return nil
 }
 
-func (c Config) SubConfig(key string) Config {
+func (c *Config) SubConfig(key string) *Config {
 	result := c.getValue(key)
 	return subConfig(result)
 }
 
-func (c Config) EachSubConfig(fun func(key string, conf Config) error) error {
+// Keys returns the keys of c in the order they are set, keys read from a
+// file are in the order they are declared.
+func (c *Config) Keys() []string {
+	return c.orderedKeys(true)
+}
+
+// EachSubConfig calls fun with each key and its sub config in the order of Keys.
+func (c *Config) EachSubConfig(fun func(key string, conf *Config) error) error {
 	if c == nil {
 		return errors.New("Config is nil")
 	}
-	for key, value := range c {
+	for _, key := range c.Keys() {
+		value, _ := c.get(key)
 		err := fun(key, subConfig(value))
 		if err != nil {
 			return err
@@ -230,11 +338,12 @@ func (c Config) EachSubConfig(fun func(key string, conf Config) error) error {
 	return nil
 }
 
-func (c Config) EachKey(fun func(key string) error) error {
+// EachKey calls fun with each key in the order of Keys.
+func (c *Config) EachKey(fun func(key string) error) error {
 	if c == nil {
 		return errors.New("Config is nil")
 	}
-	for key, _ := range c {
+	for _, key := range c.Keys() {
 		err := fun(key)
 		if err != nil {
 			return err
@@ -243,43 +352,37 @@ func (c Config) EachKey(fun func(key string) error) error {
 	return nil
 }
 
-func (c Config) KeyLen() int {
-	if c == nil {
+func (c *Config) KeyLen() int {
+	if c == nil || c.values == nil {
 		return 0
 	}
-	return len(c)
+	return c.values.Len()
 }
 
-func (c Config) Merge(key string, value interface{}) error {
+// Merge merges value to the path key of c, see ParsePath. Objects are
+// merged, other values replace the value of key. An empty key merges the
+// object value to c. Objects may be *Config or map[string]interface{}.
+func (c *Config) Merge(key string, value interface{}) error {
 	if c == nil {
 		return errors.New("Config is nil")
 	}
-	if len(key) == 0 {
-		return c.mergeConf(value)
-	}
-
-	ops := c
-	keys := strings.Split(key, ".")
-	lastKeyIndex := len(keys) - 1
 
-	for i, k := range keys {
-		if i == lastKeyIndex {
-			switch val := value.(type) {
-			case int, string, bool, float64, []string:
-				ops[k] = val
-			case map[string]interface{}, Config:
-				ops = ops.subAndCreateConfig(k)
-
-				return ops.mergeConf(value)
-			}
-		} else {
-			ops = ops.subAndCreateConfig(k)
+	value = configValue(value)
+	var keys []string
+	if len(key) > 0 {
+		var err error
+		if keys, err = ParsePath(key); err != nil {
+			return err
 		}
+	} else if subConfig(value) == nil {
+		return nil
 	}
-	return nil
+
+	_, err := mergeKeys(c, keys, value)
+	return err
 }
 
-func (c Config) SetStruct(v interface{}) error {
+func (c *Config) SetStruct(v interface{}) error {
 	if c == nil || v == nil {
 		return nil
 	}
@@ -294,6 +397,13 @@ func (c Config) SetStruct(v interface{}) error {
 	return c.EachKey(func(key string) error {
 		value := ev.FieldByName(firstRuneToUpper(key))
 		if value.IsValid() {
+			if value.Type() == durationType {
+				if dv, ok := c.Duration(key); ok {
+					value.SetInt(int64(dv))
+				}
+				return nil
+			}
+
 			switch value.Kind() {
 			case reflect.Slice:
 				if value.Type() == reflect.TypeOf([]string{}) {
@@ -329,55 +439,21 @@ func firstRuneToUpper(key string) string {
 	return string(rkey)
 }
 
-func (c Config) subAndCreateConfig(key string) (ops Config) {
-	tmpOps := c.SubConfig(key)
-	if tmpOps == nil {
-		c[key] = Config{}
-		ops, _ = c[key].(Config)
-	} else {
-		ops = tmpOps
-	}
-	return
+func (c *Config) getValue(key string) interface{} {
+	value, _ := c.lookup(key)
+	return value
 }
 
-func (c Config) mergeConf(src interface{}) error {
-	var conf Config
-	var ok bool
-	if conf, ok = src.(Config); !ok {
-		if conf, ok = src.(map[string]interface{}); !ok {
-			return nil
-		}
-	}
-
-	return conf.EachKey(func(key string) error {
-		return c.Merge(key, conf.getValue(key))
-	})
-}
-
-func (c Config) getValue(key string) interface{} {
+// lookup returns the value of the path key, found is false if key is
+// not set.
+func (c *Config) lookup(key string) (value interface{}, found bool) {
 	if len(key) == 0 || c == nil {
-		return nil
+		return nil, false
 	}
-	ops := c
-
-	keys := strings.Split(key, ".")
-	lastkeyIndex := len(keys) - 1
-	for i, key := range keys {
-		if value, ok := ops[key]; ok {
-			if i == lastkeyIndex {
-				return value
-			} else {
-				if ops, ok = value.(map[string]interface{}); ok {
-					continue
-				}
-				if ops, ok = value.(Config); ok {
-					continue
-				}
-				return nil
-			}
-		} else {
-			return nil
-		}
+
+	keys, err := ParsePath(key)
+	if err != nil {
+		return nil, false
 	}
-	return nil
+	return lookupKeys(c, keys)
 }
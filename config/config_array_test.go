@@ -0,0 +1,96 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigArrayGetters(t *testing.T) {
+	conf, err := Load("testdata/arrays.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, ok := conf.Ints("ports"); !ok || !reflect.DeepEqual(get, []int{80, 443, 8080}) {
+		t.Errorf("get ports ints, want [80 443 8080] get %v, %v", get, ok)
+	}
+	if get, ok := conf.Floats("ratios"); !ok || !reflect.DeepEqual(get, []float64{0.5, 1, 1.5}) {
+		t.Errorf("get ratios floats, want [0.5 1 1.5] get %v, %v", get, ok)
+	}
+	if get, ok := conf.Durations("timeouts"); !ok || !reflect.DeepEqual(get, []time.Duration{time.Second, 500 * time.Millisecond, 2 * time.Minute}) {
+		t.Errorf("get timeouts durations get %v, %v", get, ok)
+	}
+	if get, ok := conf.Strings("names"); !ok || !reflect.DeepEqual(get, []string{"a", "2", "true"}) {
+		t.Errorf("get names strings, want [a 2 true] get %v, %v", get, ok)
+	}
+
+	servers, ok := conf.SubConfigs("servers")
+	if !ok || len(servers) != 2 || servers[0].StringDefault("host", "") != "a" || servers[1].IntDefault("port", 0) != 81 {
+		t.Errorf("get servers sub configs get %v, %v", servers, ok)
+	}
+
+	if get, ok := conf.Ints("ratios"); ok {
+		t.Errorf("get ratios ints, want not found get %v", get)
+	}
+	if get, ok := conf.Ints("mixed"); ok || !reflect.DeepEqual(get, []int{}) {
+		t.Errorf("get mixed ints, want not found get %v", get)
+	}
+	if get, ok := conf.SubConfigs("ports"); ok || len(get) != 0 {
+		t.Errorf("get ports sub configs, want not found get %v", get)
+	}
+
+	// elements are converted like Unmarshal, an element which can not be
+	// converted is reported by Slice
+	if get, ok := conf.Bools("flags"); !ok || !reflect.DeepEqual(get, []bool{true, false}) {
+		t.Errorf("get flags bools, want [true false] get %v, %v", get, ok)
+	}
+	if get, ok := conf.Ints("numbers"); !ok || !reflect.DeepEqual(get, []int{80, 443}) {
+		t.Errorf("get numbers ints, want [80 443] get %v, %v", get, ok)
+	}
+	if get, ok := conf.Strings("objects"); ok || !reflect.DeepEqual(get, []string{}) {
+		t.Errorf("get objects strings, want not found get %v", get)
+	}
+	var objects []string
+	if err, ok := conf.Slice("objects", &objects).(*UnmarshalTypeError); !ok || err.Key != "objects.1" {
+		t.Errorf("slice objects, want type error of objects.1 get %v", err)
+	}
+
+	if get := conf.IntsDefault("notexist", []int{1}); !reflect.DeepEqual(get, []int{1}) {
+		t.Errorf("get notexist ints default, want [1] get %v", get)
+	}
+	if get := conf.FloatsDefault("mixed", []float64{2}); !reflect.DeepEqual(get, []float64{2}) {
+		t.Errorf("get mixed floats default, want [2] get %v", get)
+	}
+	if get := conf.DurationsDefault("notexist", []time.Duration{time.Second}); !reflect.DeepEqual(get, []time.Duration{time.Second}) {
+		t.Errorf("get notexist durations default get %v", get)
+	}
+	if get := conf.SubConfigsDefault("notexist", nil); get != nil {
+		t.Errorf("get notexist sub configs default, want nil get %v", get)
+	}
+}
+
+func TestConfigSlice(t *testing.T) {
+	conf, err := Load("testdata/arrays.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ports []uint16
+	if err = conf.Slice("ports", &ports); err != nil || !reflect.DeepEqual(ports, []uint16{80, 443, 8080}) {
+		t.Errorf("slice ports get %v, %v", ports, err)
+	}
+
+	var mixed []int
+	err = conf.Slice("mixed", &mixed)
+	if typeErr, ok := err.(*UnmarshalTypeError); !ok || typeErr.Key != "mixed.1" {
+		t.Errorf("slice mixed, want type error of mixed.1 get %v", err)
+	}
+
+	if err = conf.Slice("notexist", &mixed); err == nil {
+		t.Error("slice notexist, want error")
+	}
+	if err = conf.Slice("ports", mixed); err == nil {
+		t.Error("slice to non pointer, want error")
+	}
+}
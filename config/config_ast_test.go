@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/ast.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	nodes, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Node{
+		{Kind: CommentNode, Value: "server settings", Line: 1, Column: 1},
+		{Kind: FieldNode, Key: "server", Line: 2, Column: 1, Object: []*Node{
+			{Kind: FieldNode, Key: "port", Value: "8080", Line: 3, Column: 2},
+			{Kind: CommentNode, Value: "listen port", Line: 3, Column: 14},
+			{Kind: FieldNode, Key: `"a.b"`, Value: "x", Line: 4, Column: 2},
+		}},
+		{Kind: FieldNode, Key: "list", Value: "[1, 2] [3]", Line: 6, Column: 1},
+		{Kind: FieldNode, Key: "plugins", Value: "auth", Append: true, Line: 7, Column: 1},
+		{Kind: FieldNode, Key: "base", Value: "${server}", Line: 8, Column: 1, Object: []*Node{
+			{Kind: FieldNode, Key: "port", Value: "9000", Line: 8, Column: 20},
+		}},
+		{Kind: IncludeNode, Value: `required("other.conf")`, Line: 9, Column: 1},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		for i := range nodes {
+			t.Logf("%d: %+v", i, *nodes[i])
+		}
+		t.Error("parse testdata/ast.conf, get unexpected nodes")
+	}
+}
+
+func TestParseConcatenation(t *testing.T) {
+	nodes, err := Parse(strings.NewReader("l = [1] ${more}\na {x = 1} ${b}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Node{
+		{Kind: FieldNode, Key: "l", Value: "[1] ${more}", Line: 1, Column: 1},
+		{Kind: FieldNode, Key: "a", Value: "{x = 1} ${b}", Line: 2, Column: 1},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		for i := range nodes {
+			t.Logf("%d: %+v", i, *nodes[i])
+		}
+		t.Error("parse concatenation, get unexpected nodes")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse(strings.NewReader("a = 1\n}\n"))
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Line != 2 {
+		t.Errorf("want syntax error at line 2, get %v", err)
+	}
+
+	_, err = Parse(strings.NewReader("include file(a.conf)\n"))
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Line != 1 {
+		t.Errorf("want syntax error of include at line 1, get %v", err)
+	}
+}
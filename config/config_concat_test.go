@@ -0,0 +1,100 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConcatenation(t *testing.T) {
+	conf, err := Load("testdata/concat.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]interface{}{
+		"server.host":        "localhost",
+		"server.port":        float64(9000),
+		"server.tls.enabled": false,
+		"server.tls.cert":    "server.pem",
+		"base.port":          float64(8080),
+		"base.tls.cert":      nil,
+		"other.a":            float64(1),
+		"other.b":            float64(2),
+		"replaced.a":         float64(1),
+		"list":               []interface{}{float64(1), float64(2), float64(3)},
+		"more":               []interface{}{float64(1), float64(2), float64(3), float64(4), float64(5)},
+		"plugins":            []interface{}{"auth", "metrics", "localhost"},
+		"added":              []interface{}{"first"},
+		"objects": []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b", "tags": []interface{}{"x"}},
+		},
+	} {
+		if get := mapValue(conf.getValue(key)); !reflect.DeepEqual(get, want) {
+			t.Errorf("get %s value, want %#v get %#v", key, want, get)
+		}
+	}
+
+	if get, want := conf.SubConfig("server").Keys(), []string{"host", "port", "tls"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("keys of server want %v get %v", want, get)
+	}
+}
+
+func TestConcatenationInArray(t *testing.T) {
+	conf, err := Read(strings.NewReader("l = [1]\no = {x = 1}\nm = [ ${l} [2], [3] [4] ]\nn = [ ${o} {y = 2}, {a = 1} {b = 2} ]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]interface{}{
+		"m": []interface{}{[]interface{}{float64(1), float64(2)}, []interface{}{float64(3), float64(4)}},
+		"n": []interface{}{
+			map[string]interface{}{"x": float64(1), "y": float64(2)},
+			map[string]interface{}{"a": float64(1), "b": float64(2)},
+		},
+	} {
+		if get := mapValue(conf.getValue(key)); !reflect.DeepEqual(get, want) {
+			t.Errorf("get %s value, want %#v get %#v", key, want, get)
+		}
+	}
+}
+
+func TestConcatenationAfterValue(t *testing.T) {
+	conf, err := Read(strings.NewReader("more = [3]\nb = {y = 2, x = 0}\nport = 80\n" +
+		"l = [1] [2] ${more}\na = {x = 1} ${b}\nc {x = 1} ${?none}\nn = 80 ${port}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]interface{}{
+		"l": []interface{}{float64(1), float64(2), float64(3)},
+		"a": map[string]interface{}{"x": float64(0), "y": float64(2)},
+		"c": map[string]interface{}{"x": float64(1)},
+		"n": "80 80",
+	} {
+		if get := mapValue(conf.getValue(key)); !reflect.DeepEqual(get, want) {
+			t.Errorf("get %s value, want %#v get %#v", key, want, get)
+		}
+	}
+}
+
+func TestConcatenationError(t *testing.T) {
+	for _, data := range []string{
+		"a = [1]\nb = ${a} foo\n",
+		"a = x\nb = ${a} [1]\n",
+		"a = x\nb = [ ${a} [1] ]\n",
+		"a = foo [1]\n",
+		"a + 1\n",
+		"b = 1\na = {x = 1} ${b}\n",
+	} {
+		if conf, err := Read(strings.NewReader(data)); err == nil || conf != nil {
+			t.Errorf("read %q, want error and nil config, get %v, %v", data, conf, err)
+		}
+	}
+
+	_, err := Read(strings.NewReader("a {x = 1}\na += 2\n"))
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Line != 2 || synErr.Column != 1 {
+		t.Errorf("want error at the key of +=, get %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFormatSource(t *testing.T) {
+	src, err := os.ReadFile("testdata/format.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile("testdata/format.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := FormatSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, golden) {
+		t.Errorf("format testdata/format.conf, want:\n%s\nget:\n%s", golden, out)
+	}
+
+	// formatted source is not changed
+	out, err = FormatSource(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, golden) {
+		t.Errorf("format testdata/format.golden, get:\n%s", out)
+	}
+}
+
+func TestFormatKeepsConfig(t *testing.T) {
+	for _, name := range []string{"testdata/test2.conf", "testdata/concat.conf", "testdata/arrays.conf"} {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := FormatSource(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, err := Read(bytes.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		get, err := Read(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("read formatted %s: %v", name, err)
+		}
+		if !reflect.DeepEqual(want, get) {
+			t.Errorf("format %s, config changed from %v to %v", name, want, get)
+		}
+	}
+}
+
+func TestFormatSourceError(t *testing.T) {
+	_, err := FormatSource([]byte("a = 1\n}\n"))
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Errorf("want syntax error, get %v", err)
+	}
+}
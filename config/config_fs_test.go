@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := os.DirFS("testdata/fs")
+
+	conf, err := LoadFS(fsys, "app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"name":         "app",
+		"db.host":      "db1",
+		"db.pool.size": float64(10),
+	} {
+		if get := conf.getValue(key); get != want {
+			t.Errorf("get %s value, want %v get %v", key, want, get)
+		}
+	}
+
+	if _, err = LoadFS(fsys, "notexist.conf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want not exist error, get %v", err)
+	}
+
+	_, err = LoadFS(fsys, "required.conf")
+	var confErr *ConfigError
+	if !errors.As(err, &confErr) || confErr.File != "required.conf" || confErr.Line != 2 ||
+		confErr.Include != "missing.conf" || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want include error at required.conf:2, get %v", err)
+	}
+
+	_, err = LoadFS(fsys, "includebad.conf")
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) || synErr.File != "bad.conf" || synErr.Line != 2 {
+		t.Errorf("want syntax error in bad.conf, get %v", err)
+	}
+	if !errors.As(err, &confErr) || !reflect.DeepEqual(confErr.IncludeChain(), []string{"includebad.conf", "bad.conf"}) {
+		t.Errorf("want include chain, get %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestIncludeVariants(t *testing.T) {
+	loader := &Loader{Resources: fstest.MapFS{
+		"server.conf":        {Data: []byte("port = 9000\ninclude \"defaults/*.conf\"\n")},
+		"defaults/tls.conf":  {Data: []byte("tls = true\n")},
+		"defaults/skip.json": {Data: []byte("skip = true\n")},
+	}}
+
+	conf, err := loader.Load("testdata/include/app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]interface{}{
+		"db.host":     "db1",
+		"db.port":     float64(5432),
+		"http.port":   float64(8080),
+		"http.host":   "web",
+		"server.port": float64(9000),
+		"server.tls":  true,
+		"server.skip": nil,
+	} {
+		if get := conf.getValue(key); get != want {
+			t.Errorf("get %s value, want %v get %v", key, want, get)
+		}
+	}
+}
+
+func TestIncludeRequired(t *testing.T) {
+	loader := &Loader{Resources: fstest.MapFS{}}
+
+	for _, include := range []string{
+		`required("testdata/include/missing.conf")`,
+		`required(file("testdata/include/missing/*.conf"))`,
+		`required(classpath("missing.conf"))`,
+	} {
+		_, err := loader.Read(strings.NewReader("a = 1\ninclude " + include + "\n"))
+		var confErr *ConfigError
+		if !errors.As(err, &confErr) || confErr.Line != 2 {
+			t.Errorf("include %s, want include error at line 2, get %v", include, err)
+		}
+	}
+
+	for _, include := range []string{`file(missing.conf)`, `required("a.conf"`, `"a.conf" file`} {
+		if _, err := Read(strings.NewReader("include " + include + "\n")); err == nil {
+			t.Errorf("include %s, want syntax error", include)
+		}
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	_, err := Load("testdata/cycle/all.conf")
+	var confErr *ConfigError
+	if !errors.As(err, &confErr) || confErr.Line != 1 || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("want include cycle error at line 1, get %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"a.conf": {Data: []byte("include \"b.conf\"\n")},
+		"b.conf": {Data: []byte("x = 1\ninclude \"a.conf\"\n")},
+	}
+	_, err = LoadFS(fsys, "a.conf")
+	if !errors.As(err, &confErr) || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("want include cycle error, get %v", err)
+	}
+}
+
+func TestIncludeURL(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.FS(fstest.MapFS{
+		"conf/remote.conf": {Data: []byte("name = remote\ninclude \"nested.conf\"\n")},
+		"conf/nested.conf": {Data: []byte("nested = true\n")},
+	})))
+	defer server.Close()
+
+	conf, err := Read(strings.NewReader("remote {\n\tinclude url(\"" + server.URL + "/conf/remote.conf\")\n\tinclude url(\"" + server.URL + "/conf/missing.conf\")\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get := conf.StringDefault("remote.name", ""); get != "remote" {
+		t.Errorf("get remote.name, want remote get %s", get)
+	}
+	if get := conf.BoolDefault("remote.nested", false); !get {
+		t.Errorf("get remote.nested, want true get %v", get)
+	}
+
+	_, err = Read(strings.NewReader("include required(url(\"" + server.URL + "/conf/missing.conf\"))\n"))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("want not found error, get %v", err)
+	}
+}
+
+func TestIncludeURLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.conf" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := Read(strings.NewReader("include url(\"" + server.URL + "/app.conf\")\n"))
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("want service unavailable error, get %v", err)
+	}
+
+	loader := &Loader{HTTPClient: &http.Client{Timeout: 50 * time.Millisecond}}
+	if _, err = loader.Read(strings.NewReader("include url(\"" + server.URL + "/slow.conf\")\n")); err == nil {
+		t.Error("want timeout error")
+	}
+}
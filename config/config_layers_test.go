@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayers(t *testing.T) {
+	layers := NewLayers().
+		File("testdata/layers/reference.conf").
+		File("testdata/layers/application.conf").
+		OptionalFile("testdata/layers/notexist.conf").
+		Args([]string{"-v", "-Dserver.host=example.com", "-Ddb.hosts=[db2, db3]", "-Dname=hello world"})
+
+	conf, err := layers.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]interface{}{
+		"server.host":    "example.com",
+		"server.port":    float64(9000),
+		"server.timeout": "30s",
+		"db.hosts":       []interface{}{"db2", "db3"},
+		"db.pool":        "none",
+		"name":           "hello world",
+	} {
+		if get := mapValue(conf.getValue(key)); !reflect.DeepEqual(get, want) {
+			t.Errorf("get %s value, want %#v get %#v", key, want, get)
+		}
+	}
+
+	for key, want := range map[string]string{
+		"server.host":    "-Dserver.host=example.com",
+		"server.port":    "testdata/layers/application.conf",
+		"server.timeout": "testdata/layers/reference.conf",
+		"db.hosts":       "-Ddb.hosts=[db2, db3]",
+		"db.pool":        "testdata/layers/application.conf",
+	} {
+		if get, ok := layers.Origin(key); !ok || get != want {
+			t.Errorf("get %s origin, want %s get %s, %v", key, want, get, ok)
+		}
+	}
+
+	if get, ok := layers.Origin("db.pool.size"); ok {
+		t.Errorf("get db.pool.size origin, want not found get %s", get)
+	}
+}
+
+func TestLayersOverrideSubstitution(t *testing.T) {
+	layers := NewLayers().
+		File("testdata/layers/reference.conf").
+		File("testdata/layers/application.conf").
+		Overrides("server.host=example.com", "server.url=${server.host}\":\"${server.port}", "db.hosts=${db.hosts} [db4]", "-Dname=${?name}x")
+
+	conf, err := layers.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, _ := conf.String("server.url"); get != "example.com:9000" {
+		t.Errorf("get server.url, want example.com:9000 get %s", get)
+	}
+	if get, _ := conf.Strings("db.hosts"); !reflect.DeepEqual(get, []string{"db1", "db4"}) {
+		t.Errorf("get db.hosts, want [db1 db4] get %v", get)
+	}
+	if get, _ := conf.String("name"); get != "x" {
+		t.Errorf("get name, want x get %s", get)
+	}
+	if get, _ := layers.Origin("server.url"); get != "server.url=${server.host}\":\"${server.port}" {
+		t.Errorf("get server.url origin, get %s", get)
+	}
+
+	if _, err := NewLayers().Overrides("a=${b}").Build(); err == nil {
+		t.Error("want error of override with unresolved substitution")
+	}
+}
+
+func TestLayersLateSubstitution(t *testing.T) {
+	layers := NewLayers().
+		File("testdata/layers/reference.conf").
+		File("testdata/layers/url.conf").
+		Overrides("-Dserver.url=${server.host}", "-Dserver.host=example.com", "-Dcopy=${server}")
+
+	conf, err := layers.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, _ := conf.String("db.url"); get != "jdbc://db0" {
+		t.Errorf("get db.url, want jdbc://db0 get %s", get)
+	}
+	if get, _ := conf.String("server.url"); get != "example.com" {
+		t.Errorf("get server.url, want example.com get %s", get)
+	}
+	if get, _ := layers.Origin("db.url"); get != "testdata/layers/url.conf" {
+		t.Errorf("get db.url origin, get %s", get)
+	}
+	if get, _ := layers.Origin("copy.port"); get != "-Dcopy=${server}" {
+		t.Errorf("get copy.port origin, get %s", get)
+	}
+}
+
+func TestLayersError(t *testing.T) {
+	if _, err := NewLayers().File("testdata/layers/notexist.conf").Build(); err == nil {
+		t.Error("want error of not exist file")
+	}
+
+	if _, err := NewLayers().Overrides("-Dserver.port").Build(); err == nil {
+		t.Error("want error of override without value")
+	}
+}
@@ -5,20 +5,20 @@ import (
 	"testing"
 )
 
-var mergeConf = Config{
-	"test1": Config{
+var mergeConf = FromMap(map[string]interface{}{
+	"test1": map[string]interface{}{
 		"num":     1,
 		"comment": "#",
 		"ok":      true,
-		"cover": Config{
+		"cover": map[string]interface{}{
 			"fnum": 12.58,
 		},
 	},
-	"test2": Config{
+	"test2": map[string]interface{}{
 		"mylist": []string{"1", "2", "3"},
 	},
-	"test3": Config{
-		"nums": Config{
+	"test3": map[string]interface{}{
+		"nums": map[string]interface{}{
 			"num1": -0.123,
 			"num2": 3.14e+2,
 			"num3": 3.14e-3,
@@ -27,7 +27,7 @@ var mergeConf = Config{
 			"num6": 0e6,
 		},
 	},
-}
+})
 
 func TestMergeInt(t *testing.T) {
 	if get, ok := mergeConf.Int("test1.num"); !ok || get != 1 {
@@ -66,16 +66,16 @@ func TestMergeConfig(t *testing.T) {
 		t.Errorf("get test1.cover.fnum float value, want 12.58 get %v, %v", get, ok)
 	}
 
-	mergeConf.Merge("test1", Config{
+	mergeConf.Merge("test1", FromMap(map[string]interface{}{
 		"num": 1,
 		"ok":  false,
 		"cover": map[string]interface{}{
 			"fnum": 0.001,
 		},
-		"list": Config{
+		"list": map[string]interface{}{
 			"strlist": []string{"11", "22", "33"},
 		},
-	})
+	}))
 
 	if get, ok := mergeConf.Int("test1.num"); !ok || get != 1 {
 		t.Errorf("get test1.num int value, want 1 get %v, %v", get, ok)
@@ -95,11 +95,11 @@ func TestMergeConfig(t *testing.T) {
 }
 
 func TestMergeRootConfig(t *testing.T) {
-	mergeConf.Merge("", Config{
-		"test2": Config{
+	mergeConf.Merge("", FromMap(map[string]interface{}{
+		"test2": map[string]interface{}{
 			"mylist1": []string{"11", "12", "13"},
 		},
-	})
+	}))
 
 	if get, ok := mergeConf.Strings("test2.mylist1"); !ok || !reflect.DeepEqual(get, []string{"11", "12", "13"}) {
 		t.Errorf("get test2.mylist1 string list value, want [11, 12, 13] get %v, %v", get, ok)
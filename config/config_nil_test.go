@@ -7,9 +7,9 @@ import (
 )
 
 func TestNil(t *testing.T) {
-	var conf Config = nil
+	var conf *Config = nil
 
-	err := conf.EachSubConfig(func(key string, c Config) error {
+	err := conf.EachSubConfig(func(key string, c *Config) error {
 		return nil
 	})
 	if err == nil || !strings.Contains(fmt.Sprintf("%v", err), "Config is nil") {
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeysInFileOrder(t *testing.T) {
+	conf, err := Load("testdata/order.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, want := conf.Keys(), []string{"appenders", "c", "b", "a"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("keys want %v get %v", want, get)
+	}
+	if get, want := conf.SubConfig("a").Keys(), []string{"z", "y"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("keys of a want %v get %v", want, get)
+	}
+
+	for i := 0; i < 10; i++ {
+		var get []string
+		conf.SubConfig("appenders").EachSubConfig(func(key string, sub *Config) error {
+			get = append(get, key+"="+sub.StringDefault("type", ""))
+			return nil
+		})
+		if want := []string{"zconsole=Console", "file=File", "mail=Mail"}; !reflect.DeepEqual(get, want) {
+			t.Fatalf("each sub config want %v get %v", want, get)
+		}
+	}
+}
+
+func TestKeysMergeOrder(t *testing.T) {
+	conf := &Config{}
+	conf.Merge("y", 1)
+	conf.Merge("x.b", "b")
+	conf.Merge("x.a", "a")
+	conf.Merge("w", 2)
+
+	var get []string
+	conf.EachKey(func(key string) error {
+		get = append(get, key)
+		return nil
+	})
+	if want := []string{"y", "x", "w"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("each key want %v get %v", want, get)
+	}
+	if get, want := conf.SubConfig("x").Keys(), []string{"b", "a"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("keys of x want %v get %v", want, get)
+	}
+
+	// a merged key keeps its place, new keys are added after it
+	conf.Merge("b", 1)
+	conf.Merge("x", 1)
+	conf.Merge("a", 1)
+	if get, want := conf.Keys(), []string{"y", "x", "w", "b", "a"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("keys want %v get %v", want, get)
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	m := map[string]interface{}{"b": 1, "a": map[string]interface{}{"d": 2, "c": []interface{}{map[string]interface{}{"x": 3}}}}
+	conf := FromMap(m)
+	if get, want := conf.Keys(), []string{"a", "b"}; !reflect.DeepEqual(get, want) {
+		t.Errorf("keys want %v get %v", want, get)
+	}
+	if x, _ := conf.Int("a.c.0.x"); x != 3 {
+		t.Errorf("want a.c.0.x 3, get %d", x)
+	}
+	if get := conf.Map(); !reflect.DeepEqual(get, m) {
+		t.Errorf("map want %v get %v", m, get)
+	}
+}
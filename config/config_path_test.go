@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for path, want := range map[string][]string{
+		"a":                    {"a"},
+		"a.b.c":                {"a", "b", "c"},
+		`hosts."a.b.com".port`: {"hosts", "a.b.com", "port"},
+		`a."b"c.d`:             {"a", "bc", "d"},
+		`a."".b`:               {"a", "", "b"},
+		`"a\"b"`:               {`a"b`},
+		"servers.0.host":       {"servers", "0", "host"},
+	} {
+		if get, err := ParsePath(path); err != nil || !reflect.DeepEqual(get, want) {
+			t.Errorf("parse path %s, want %q get %q, %v", path, want, get, err)
+		}
+	}
+
+	for _, path := range []string{"", "a..b", ".a", "a.", `a."b`} {
+		if get, err := ParsePath(path); err == nil {
+			t.Errorf("parse path %s, want error get %q", path, get)
+		}
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	conf, err := Load("testdata/path.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]interface{}{
+		`hosts."a.b.com".port`: float64(80),
+		`hosts.""`:             "empty",
+		"servers.0.host":       "a",
+		"servers.1.host":       "b",
+		"servers.0.tags.1":     "y",
+		"servers.2.host":       nil,
+		"servers.01.host":      nil,
+		"first":                "a",
+		"web":                  float64(80),
+	} {
+		if get := conf.getValue(key); get != want {
+			t.Errorf("get %s value, want %v get %v", key, want, get)
+		}
+	}
+
+	if sub := conf.SubConfig(`hosts."a.b.com"`); sub.IntDefault("port", 0) != 80 {
+		t.Errorf("get sub config of a.b.com get %v", sub)
+	}
+
+	if err = conf.Merge(`hosts."c.d.com".port`, 81); err != nil || conf.IntDefault(`hosts."c.d.com".port`, 0) != 81 {
+		t.Errorf("merge c.d.com port get %v", err)
+	}
+	if err = conf.Merge("servers.1.port", 8080); err != nil || conf.IntDefault("servers.1.port", 0) != 8080 {
+		t.Errorf("merge servers.1.port get %v", err)
+	}
+	if err = conf.Merge("servers.0.tags.0", "z"); err != nil || conf.StringDefault("servers.0.tags.0", "") != "z" {
+		t.Errorf("merge servers.0.tags.0 get %v", err)
+	}
+	if err = conf.Merge("servers.5.host", "c"); err == nil {
+		t.Error("merge servers.5.host, want out of range error")
+	}
+	if err = conf.Merge("a..b", 1); err == nil {
+		t.Error("merge a..b, want path error")
+	}
+}
+
+func TestQuotedKeyPath(t *testing.T) {
+	conf, err := Read(strings.NewReader("\"a.b\" = x\n\"a.b\" = ${\"a.b\"}y\nhosts {\n  # port of c.d\n  \"c.d\" = 80 # trailing\n}\ncopy = ${hosts.\"c.d\"}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, ok := conf.String(`"a.b"`); !ok || get != "xy" {
+		t.Errorf("get \"a.b\" string value, want xy get %s, %v", get, ok)
+	}
+	if get, ok := conf.Int("copy"); !ok || get != 80 {
+		t.Errorf("get copy int value, want 80 get %d, %v", get, ok)
+	}
+
+	var hocon bytes.Buffer
+	if err = Render(&hocon, conf.SubConfig("hosts"), &RenderOptions{Format: FormatHOCON, Comments: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# port of c.d\n\"c.d\" = 80 # trailing\n"; hocon.String() != want {
+		t.Errorf("render hosts, want %q get %q", want, hocon.String())
+	}
+}
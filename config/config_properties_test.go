@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadProperties(t *testing.T) {
+	conf, err := LoadProperties("testdata/app.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"port": "8080",
+			"name": "demo server",
+		},
+		"servers":         map[string]interface{}{"0": "a", "1": "b"},
+		"path":            `c:\temp`,
+		"key with spaces": "été",
+		"empty":           "",
+	}
+	if !reflect.DeepEqual(conf.Map(), want) {
+		t.Errorf("want %v, get %v", want, conf.Map())
+	}
+
+	var server struct {
+		Host string
+		Port int
+	}
+	if err = Unmarshal(conf.SubConfig("server"), &server); err != nil || server.Port != 8080 {
+		t.Errorf("want server.port 8080, get %v, %v", server, err)
+	}
+	if servers, found := conf.Strings("servers"); !found || !reflect.DeepEqual(servers, []string{"a", "b"}) {
+		t.Errorf("want servers [a b], get %v, %v", servers, found)
+	}
+	if keys := conf.Keys(); !reflect.DeepEqual(keys, []string{"server", "servers", "path", "key with spaces", "empty"}) {
+		t.Errorf("want keys in file order, get %v", keys)
+	}
+
+	conf, err = ReadProperties(strings.NewReader("sparse.0 = a\nsparse.2 = c\nmixed.0 = a\nmixed.name = b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"sparse", "mixed"} {
+		var elems []string
+		if err = conf.Slice(key, &elems); err == nil {
+			t.Errorf("%s: want array index error, get %v", key, elems)
+		}
+	}
+
+	_, err = ReadProperties(strings.NewReader("a = 1\nb = \\u00zz\n"))
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Line != 2 {
+		t.Errorf("want syntax error at line 2, get %v", err)
+	}
+}
+
+func TestRenderProperties(t *testing.T) {
+	conf, err := Read(strings.NewReader(`
+server { host = localhost, port = 8080 }
+list = [a, { b = " x=1" }]
+empty = []
+brackets = "[]"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = Render(&buf, conf, &RenderOptions{Format: FormatProperties, KeepOrder: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `server.host=localhost
+server.port=8080
+list.0=a
+list.1.b=\ x\=1
+empty=[]
+brackets=\[]
+`
+	if buf.String() != want {
+		t.Errorf("want:\n%s\nget:\n%s", want, buf.String())
+	}
+
+	props, err := ReadProperties(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := props.String("list.1.b"); b != " x=1" {
+		t.Errorf("want list.1.b %q, get %q", " x=1", b)
+	}
+	if empty, found := props.Strings("empty"); !found || len(empty) != 0 {
+		t.Errorf("want empty array, get %v, %v", empty, found)
+	}
+	if b, _ := props.String("brackets"); b != "[]" {
+		t.Errorf("want brackets %q, get %q", "[]", b)
+	}
+
+	err = Render(&buf, FromMap(map[string]interface{}{"a.b": 1}), &RenderOptions{Format: FormatProperties})
+	if err == nil {
+		t.Error("want error of key with '.'")
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	conf, err := Load("testdata/test2.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Merge(`"a.b"`, map[string]interface{}{"c": 1, "empty": map[string]interface{}{}})
+
+	flat := conf.Flatten()
+	if flat["test2.user.age"] != 1.0 || flat[`"a.b".c`] != 1 {
+		t.Errorf("get unexpected flat values %v", flat)
+	}
+	if _, ok := flat["test2.mylist"].([]interface{}); !ok {
+		t.Errorf("want array value of test2.mylist, get %v", flat["test2.mylist"])
+	}
+
+	unflat, err := Unflatten(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, get bytes.Buffer
+	Render(&want, conf, nil)
+	Render(&get, unflat, nil)
+	if want.String() != get.String() {
+		t.Errorf("want %s, get %s", want.String(), get.String())
+	}
+
+	unflat, err = Unflatten(map[string]interface{}{"a.b": 2, "a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := unflat.Int("a.b"); b != 2 {
+		t.Errorf("want object wins, get %v", unflat)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	conf, err := Load("testdata/render.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts *RenderOptions
+		want string
+	}{
+		{nil, `{"app.name":"demo","features":["a","b"],"server":{"host":"localhost","port":8080}}`},
+		{&RenderOptions{Format: FormatPrettyJSON, Indent: "  "}, `{
+  "app.name": "demo",
+  "features": [
+    "a",
+    "b"
+  ],
+  "server": {
+    "host": "localhost",
+    "port": 8080
+  }
+}
+`},
+		{&RenderOptions{Format: FormatHOCON}, `"app.name" = "demo"
+features = ["a", "b"]
+server {
+	host = "localhost"
+	port = 8080
+}
+`},
+		{&RenderOptions{Format: FormatHOCON, KeepOrder: true, Comments: true}, `# server settings
+server {
+	port = 8080 # listen port
+	host = "localhost"
+}
+
+# enabled features
+features = ["a", "b"]
+"app.name" = "demo"
+`},
+		{&RenderOptions{Format: FormatYAML, KeepOrder: true}, `server:
+  port: 8080
+  host: localhost
+features:
+  - a
+  - b
+app.name: demo
+`},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := Render(&buf, conf, test.opts); err != nil {
+			t.Fatalf("%d: render error: %v", i, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("%d: render want:\n%s\nget:\n%s", i, test.want, buf.String())
+		}
+	}
+}
+
+func TestRenderHOCONRoundTrip(t *testing.T) {
+	conf, err := Load("testdata/multifile.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hocon, want, get bytes.Buffer
+	if err = Render(&hocon, conf, &RenderOptions{Format: FormatHOCON, Comments: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	reread, err := Read(&hocon)
+	if err != nil {
+		t.Fatalf("read rendered hocon error: %v", err)
+	}
+
+	Render(&want, conf, nil)
+	Render(&get, reread, nil)
+	if want.String() != get.String() {
+		t.Errorf("round trip want:\n%s\nget:\n%s", want.String(), get.String())
+	}
+}
+
+func TestRenderYAML(t *testing.T) {
+	conf, err := Read(strings.NewReader(`
+servers = [{ host = a, tags = [x, y] }, [1, 2], {}]
+empty = []
+words = ["true", "8080", "a b", "", "a:b"]
+"key: 1" = null
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = Render(&buf, conf, &RenderOptions{Format: FormatYAML, KeepOrder: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `servers:
+  - host: a
+    tags:
+      - x
+      - y
+  - - 1
+    - 2
+  - {}
+empty: []
+words:
+  - "true"
+  - "8080"
+  - "a b"
+  - ""
+  - "a:b"
+"key: 1": null
+`
+	if buf.String() != want {
+		t.Errorf("want:\n%s\nget:\n%s", want, buf.String())
+	}
+}
+
+func TestRenderEmptyObject(t *testing.T) {
+	conf, err := Read(strings.NewReader(`
+empty {}
+a = {}
+b { c {} }
+kept { x = 1 }
+kept {}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = Render(&buf, conf, &RenderOptions{Format: FormatHOCON, KeepOrder: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `empty {}
+a {}
+b {
+	c {}
+}
+kept {
+	x = 1
+}
+`
+	if buf.String() != want {
+		t.Errorf("want:\n%s\nget:\n%s", want, buf.String())
+	}
+
+	reread, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reread.Map(), conf.Map()) {
+		t.Errorf("round trip want %v, get %v", conf.Map(), reread.Map())
+	}
+}
@@ -7,20 +7,20 @@ import (
 	"testing"
 )
 
-var scriptConf = Config{
-	"test1": Config{
+var scriptConf = FromMap(map[string]interface{}{
+	"test1": map[string]interface{}{
 		"num":     1,
 		"comment": "#",
 		"ok":      true,
-		"cover": Config{
+		"cover": map[string]interface{}{
 			"fnum": 12.58,
 		},
 	},
-	"test2": Config{
+	"test2": map[string]interface{}{
 		"mylist": []string{"1", "2", "3"},
 	},
-	"test3": Config{
-		"nums": Config{
+	"test3": map[string]interface{}{
+		"nums": map[string]interface{}{
 			"num1": -0.123,
 			"num2": 3.14e+2,
 			"num3": 3.14e-3,
@@ -29,7 +29,7 @@ var scriptConf = Config{
 			"num6": 0e6,
 		},
 	},
-}
+})
 
 func TestScriptConfigGetInt(t *testing.T) {
 	// test get ok
@@ -172,7 +172,7 @@ func TestScriptConfigEachAndKeyLen(t *testing.T) {
 	subConf := scriptConf.SubConfig("test3.nums")
 
 	if subConf.KeyLen() > 0 {
-		err := subConf.EachSubConfig(func(key string, conf Config) error {
+		err := subConf.EachSubConfig(func(key string, conf *Config) error {
 			if !strings.HasPrefix(key, "num") {
 				return errors.New("unaccept key in each config test: " + key)
 			}
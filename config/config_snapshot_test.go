@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	conf, err := Load("testdata/test2.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap := NewSnapshot(conf)
+	conf.Merge("test2.num", 3)
+	if num := snap.IntDefault("test2.num", 0); num != 2 {
+		t.Errorf("want snapshot not changed by config, get test2.num %d", num)
+	}
+
+	// the copy of Config does not change the snapshot
+	snap.Config().Merge("test2.num", 4)
+	if num := snap.IntDefault("test2.num", 0); num != 2 {
+		t.Errorf("want snapshot not changed by its config, get test2.num %d", num)
+	}
+
+	user := snap.SubConfig("test2.user")
+	if name := user.StringDefault("name", ""); name != "彭毅" {
+		t.Errorf("want test2.user.name 彭毅, get %s", name)
+	}
+	if keys := snap.SubConfig("test2").Keys(); keys[0] != "comment" || keys[len(keys)-1] != "empty4" {
+		t.Errorf("want keys in file order, get %v", keys)
+	}
+	if list, _ := snap.Strings("test2.mylist"); !reflect.DeepEqual(list, []string{"1", "2", "3"}) {
+		t.Errorf("want test2.mylist [1 2 3], get %v", list)
+	}
+
+	var nilSnap *Snapshot
+	if _, found := nilSnap.String("a"); found {
+		t.Error("want nothing found in nil snapshot")
+	}
+}
+
+func TestSnapshotWith(t *testing.T) {
+	snap := NewSnapshot(FromMap(map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": map[string]interface{}{"d": 2}},
+		"e": map[string]interface{}{"f": 3},
+		"l": []interface{}{map[string]interface{}{"x": 1}},
+	}))
+
+	next, err := snap.With("a.b", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := next.IntDefault("a.b", 0); b != 10 {
+		t.Errorf("want a.b 10, get %d", b)
+	}
+	if b := snap.IntDefault("a.b", 0); b != 1 {
+		t.Errorf("want a.b of old snapshot 1, get %d", b)
+	}
+	if d := next.IntDefault("a.c.d", 0); d != 2 {
+		t.Errorf("want a.c.d 2, get %d", d)
+	}
+	// objects not on the path are shared
+	if snap.conf.SubConfig("e") != next.conf.SubConfig("e") {
+		t.Error("want object e shared by snapshots")
+	}
+
+	next, err = next.With("a", FromMap(map[string]interface{}{"g": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := next.Bool("a.g"); !g || next.IntDefault("a.b", 0) != 10 {
+		t.Errorf("want a merged, get %v", next.conf)
+	}
+
+	next, err = next.With("l.0.x", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := next.IntDefault("l.0.x", 0); x != 2 {
+		t.Errorf("want l.0.x 2, get %d", x)
+	}
+	if x := snap.IntDefault("l.0.x", 0); x != 1 {
+		t.Errorf("want l.0.x of old snapshot 1, get %d", x)
+	}
+
+	if _, err = snap.With("l.1.x", 2); err == nil {
+		t.Error("want error of index out of range")
+	}
+}
+
+func TestSnapshotHolder(t *testing.T) {
+	var holder SnapshotHolder
+	if holder.Load() != nil {
+		t.Error("want nil snapshot of zero holder")
+	}
+	holder.Store(NewSnapshot(FromMap(map[string]interface{}{"n": 0})))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			holder.Update(func(s *Snapshot) (*Snapshot, error) {
+				return s.With("n", s.IntDefault("n", 0)+1)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			holder.Load().IntDefault("n", 0)
+		}()
+	}
+	wg.Wait()
+
+	if n := holder.Load().IntDefault("n", 0); n != 10 {
+		t.Errorf("want n 10, get %d", n)
+	}
+}
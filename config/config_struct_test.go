@@ -25,7 +25,7 @@ func TestSetStruct(t *testing.T) {
 		HttpAddr: "localhost",
 	}
 
-	conf := Config{
+	conf := FromMap(map[string]interface{}{
 		"appName":    "sample",
 		"HttpAddr":   "127.0.0.1",
 		"httpSslKey": "test",
@@ -33,7 +33,7 @@ func TestSetStruct(t *testing.T) {
 		"httpPort":   9000,
 		"seeds":      []string{"1", "2", "3"},
 		"floatNum":   1.18,
-	}
+	})
 
 	if err := conf.SetStruct(&test); err != nil {
 		t.Error(err)
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSubstitution(t *testing.T) {
+	conf, err := Load("testdata/substitution.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"app.host":       "localhost",
+		"app.url":        "http://localhost:8080/index",
+		"app.path":       "/usr/bin:/usr/local/bin",
+		"db.host":        "localhost",
+		"db.user.home":   "/home/root",
+		"db.user.dbhost": "localhost",
+		"copy.name":      "root",
+	}
+	for key, value := range want {
+		if get := conf.getValue(key); get != value {
+			t.Errorf("get %s value, want %v get %v", key, value, get)
+		}
+	}
+
+	if get, ok := conf.Int("db.port"); !ok || get != 3306 {
+		t.Errorf("get db.port int value, want 3306 get %d, %v", get, ok)
+	}
+
+	if _, ok := conf.lookup("app.name"); ok {
+		t.Error("optional substitution app.name must not be set")
+	}
+
+	if get, _ := conf.Strings("db.hosts"); !reflect.DeepEqual(get, []string{"localhost", "${app.host}"}) {
+		t.Errorf("get db.hosts value, want [localhost ${app.host}] get %v", get)
+	}
+}
+
+func TestSubstitutionSelfReference(t *testing.T) {
+	conf, err := Read(strings.NewReader("a = 1\na = ${a}\nb = ${?b}\nc = x\nc = ${c}y"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, ok := conf.Int("a"); !ok || get != 1 {
+		t.Errorf("get a int value, want 1 get %d, %v", get, ok)
+	}
+
+	if _, ok := conf.lookup("b"); ok {
+		t.Error("optional self reference b must not be set")
+	}
+
+	if get, ok := conf.String("c"); !ok || get != "xy" {
+		t.Errorf("get c string value, want xy get %s, %v", get, ok)
+	}
+}
+
+func TestSubstitutionError(t *testing.T) {
+	_, err := Load("testdata/substcycle.conf")
+	if _, ok := err.(*SyntaxError); !ok || !strings.Contains(err.Error(), "cycle in substitution") {
+		t.Errorf("want cycle error, get %v", err)
+	}
+
+	_, err = Read(strings.NewReader("a = ${b}"))
+	if _, ok := err.(*SyntaxError); !ok || err.Error() != "1:5: could not resolve substitution ${b} to a value" {
+		t.Errorf("want resolve error, get %v", err)
+	}
+
+	for _, buf := range []string{`${b}x\q`, `${b}"\u12"`} {
+		if value, err := concatValue([]byte(buf)); err == nil {
+			t.Errorf("%s: want invalid escape error, get %v", buf, value)
+		}
+	}
+}
+
+func TestSubstitutionEnv(t *testing.T) {
+	env := map[string]string{"HOME": "/home/py", "HTTP_PORT": "9000"}
+	loader := &Loader{LookupEnv: func(key string) (value string, found bool) {
+		value, found = env[key]
+		return
+	}}
+
+	conf, err := loader.Read(strings.NewReader("home = ${?HOME}\ndir = ${HOME}\"/conf\"\nport = 80\nport = ${?HTTP_PORT}\nhost = ${?HOST}\nPORT = 8080\nnext = ${PORT}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get, ok := conf.String("home"); !ok || get != "/home/py" {
+		t.Errorf("get home string value, want /home/py get %s, %v", get, ok)
+	}
+
+	if get, ok := conf.String("dir"); !ok || get != "/home/py/conf" {
+		t.Errorf("get dir string value, want /home/py/conf get %s, %v", get, ok)
+	}
+
+	if get, ok := conf.String("port"); !ok || get != "9000" {
+		t.Errorf("get port string value, want 9000 get %s, %v", get, ok)
+	}
+
+	if _, ok := conf.lookup("host"); ok {
+		t.Error("optional substitution host must not be set")
+	}
+
+	// config tree has priority over environment
+	if get, ok := conf.Int("next"); !ok || get != 8080 {
+		t.Errorf("get next int value, want 8080 get %d, %v", get, ok)
+	}
+}
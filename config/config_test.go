@@ -31,7 +31,7 @@ func compareJsonAndConfig(t *testing.T, jsonFile string, confFile string) {
 		t.Error(err)
 	}
 
-	if !reflect.DeepEqual(Config(json.(map[string]interface{})), conf) {
+	if !reflect.DeepEqual(json, conf.Map()) {
 		t.Errorf("\nwant %v\n got %v", json, conf)
 	}
 }
@@ -49,22 +49,73 @@ func TestLoadMultiFile(t *testing.T) {
 }
 
 func TestLoadNotExistFile(t *testing.T) {
-	_, err := Load("testdata/includenoexistfile.conf")
-	if err != nil && !strings.HasPrefix(err.Error(), "error in load include:") {
+	// a missing include which is not required is skipped
+	conf, err := Load("testdata/includenoexistfile.conf")
+	if err != nil || conf.IntDefault("test1.num", 0) != 1 {
+		t.Fatalf("want missing include skipped, get %v", err)
+	}
+
+	_, err = Load("testdata/includerequired.conf")
+	confErr, ok := err.(*ConfigError)
+	if !ok || confErr.File != "testdata/includerequired.conf" || confErr.Line != 3 || confErr.Column != 1 {
+		t.Fatalf("want include error at testdata/includerequired.conf:3:1, get %v", err)
+	}
+
+	if confErr.Include != "testdata/test1.conf" || !os.IsNotExist(errors.Unwrap(err)) {
 		t.Error(err)
 	}
 }
 
 func TestScannerError(t *testing.T) {
 	_, err := Load("testdata/scanerr.conf")
-	if err != nil && !strings.HasPrefix(err.Error(), "invalid character '\"'") {
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Line != 21 || synErr.Column != 12 ||
+		err.Error() != "testdata/scanerr.conf:21:12: invalid character '\"' after object key:value pair" {
 		t.Error(err)
 	}
 
 	_, err = Load("testdata/scanerr1.conf")
-	if err != nil && !strings.HasPrefix(err.Error(), "invalid character '+'") {
+	if synErr, ok := err.(*SyntaxError); !ok || synErr.Line != 21 || synErr.Column != 12 ||
+		!strings.Contains(err.Error(), "invalid character '+'") {
+		t.Error(err)
+	}
+
+	_, err = Read(strings.NewReader("db {\n\t\"port\" 5432\n}"))
+	if err == nil || err.Error() != "2:9: expected ':' or '=' after key \"db.port\"" {
 		t.Error(err)
 	}
+
+	for conf, want := range map[string]string{
+		"db {\n port 80\n}":    "2:9: expected ':' or '=' after key \"db.port 80\"",
+		"a = {":                "1:5: object is not closed by '}'",
+		"a {\n  b = 1\n":       "1:3: object is not closed by '}'",
+		"a = [1, 2\nb = 3":     "1:5: array is not closed by ']'",
+		"a = [{x = 1}, {y = 2": "1:15: object is not closed by '}'",
+		"a = [[1], [2\n":       "1:11: array is not closed by ']'",
+	} {
+		_, err = Read(strings.NewReader(conf))
+		if _, ok := err.(*SyntaxError); !ok || err.Error() != want {
+			t.Errorf("read %q, want error %s, get %v", conf, want, err)
+		}
+	}
+}
+
+func TestIncludeScannerError(t *testing.T) {
+	_, err := Load("testdata/includescanerr.conf")
+
+	var synErr *SyntaxError
+	if !errors.As(err, &synErr) || synErr.File != "testdata/scanerr.conf" || synErr.Line != 21 {
+		t.Errorf("want syntax error in testdata/scanerr.conf, get %v", err)
+	}
+
+	var confErr *ConfigError
+	if !errors.As(err, &confErr) {
+		t.Fatalf("want include error, get %v", err)
+	}
+
+	want := []string{"testdata/includescanerr.conf", "testdata/test/include.conf", "testdata/scanerr.conf"}
+	if chain := confErr.IncludeChain(); !reflect.DeepEqual(chain, want) {
+		t.Errorf("want include chain %v, get %v", want, chain)
+	}
 }
 
 func TestConfigGetInt(t *testing.T) {
@@ -273,7 +324,7 @@ func TestConfigEachAndKeyLen(t *testing.T) {
 	subConf := conf.SubConfig("test3.nums")
 
 	if subConf.KeyLen() > 0 {
-		err := subConf.EachSubConfig(func(key string, conf Config) error {
+		err := subConf.EachSubConfig(func(key string, conf *Config) error {
 			if !strings.HasPrefix(key, "num") {
 				return errors.New("unaccept key in each config test: " + key)
 			}
@@ -288,7 +339,7 @@ func TestConfigEachAndKeyLen(t *testing.T) {
 	}
 }
 
-func useReadGetConfig(fileName string) (conf Config, err error) {
+func useReadGetConfig(fileName string) (conf *Config, err error) {
 	reader, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
@@ -302,3 +353,22 @@ func useReadGetConfig(fileName string) (conf Config, err error) {
 
 	return conf, nil
 }
+
+func TestArrayElements(t *testing.T) {
+	conf, err := Read(strings.NewReader("a = []\nb = [[1, 2], [3, []]]\nc = [\n\t{x = 1} # comment\n\t{y {z = true}}\n]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"a": []interface{}{},
+		"b": []interface{}{[]interface{}{1.0, 2.0}, []interface{}{3.0, []interface{}{}}},
+		"c": []interface{}{
+			map[string]interface{}{"x": 1.0},
+			map[string]interface{}{"y": map[string]interface{}{"z": true}},
+		},
+	}
+	if !reflect.DeepEqual(conf.Map(), want) {
+		t.Errorf("\nwant %v\n got %v", want, conf.Map())
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigGetDuration(t *testing.T) {
+	conf, err := Load("testdata/units.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]time.Duration{
+		"http.timeout": 30 * time.Second,
+		"http.idle":    10 * time.Minute,
+		"http.retry":   90 * time.Minute,
+		"http.delay":   1500 * time.Millisecond,
+		"http.days":    48 * time.Hour,
+		"http.gotime":  90 * time.Minute,
+	} {
+		if get, ok := conf.Duration(key); !ok || get != want {
+			t.Errorf("get %s duration value, want %v get %v, %v", key, want, get, ok)
+		}
+	}
+
+	if get, ok := conf.Duration("bad"); ok {
+		t.Errorf("get bad duration value, want not found get %v", get)
+	}
+
+	if conf.DurationDefault("http.none", time.Second) != time.Second {
+		t.Error("get duration default error")
+	}
+}
+
+func TestConfigGetBytes(t *testing.T) {
+	conf, err := Load("testdata/units.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]int64{
+		"body.max":  10000000,
+		"body.buf":  512 * 1024,
+		"body.page": 4096,
+		"body.min":  100,
+		"body.disk": 1500000000,
+		"body.mem":  2 * 1024 * 1024,
+	} {
+		if get, ok := conf.Bytes(key); !ok || get != want {
+			t.Errorf("get %s bytes value, want %d get %d, %v", key, want, get, ok)
+		}
+	}
+
+	if get, ok := conf.Bytes("bad"); ok {
+		t.Errorf("get bad bytes value, want not found get %v", get)
+	}
+
+	if conf.BytesDefault("body.none", 1024) != 1024 {
+		t.Error("get bytes default error")
+	}
+}
+
+func TestSetStructDuration(t *testing.T) {
+	test := struct {
+		Timeout time.Duration
+		Idle    time.Duration
+	}{}
+
+	if err := (FromMap(map[string]interface{}{"timeout": "30s", "idle": 200})).SetStruct(&test); err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Timeout != 30*time.Second || test.Idle != 200*time.Millisecond {
+		t.Errorf("set duration fields error, get %v", test)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*l = 1
+	case "debug":
+		*l = 2
+	default:
+		return errors.New("unknown level " + string(text))
+	}
+	return nil
+}
+
+type testServer struct {
+	Host string
+	Port uint16
+}
+
+type testHttp struct {
+	Port        int
+	Addr        string
+	Timeout     time.Duration
+	ReadTimeout time.Duration
+}
+
+type testLimits struct {
+	Small int8
+	Big   int64
+	Ratio float32
+}
+
+type testUnmarshal struct {
+	AppName string `hocon:"appName"`
+	Http    *testHttp
+	Servers []testServer
+	Limits  testLimits
+	Labels  map[string]string
+	Level   testLevel
+	Skip    string `hocon:"-"`
+	Weights [3]float64
+	Missing string
+}
+
+func TestUnmarshal(t *testing.T) {
+	conf, err := Load("testdata/unmarshal.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := testUnmarshal{Missing: "default"}
+	if err := Unmarshal(conf, &test); err != nil {
+		t.Fatal(err)
+	}
+
+	want := testUnmarshal{
+		AppName: "sample",
+		Http:    &testHttp{9000, "127.0.0.1", 30 * time.Second, 1500 * time.Millisecond},
+		Servers: []testServer{{"a", 1}, {"b", 2}},
+		Limits:  testLimits{8, 4294967296, 0.5},
+		Labels:  map[string]string{"env": "prod", "zone": "cn"},
+		Level:   2,
+		Weights: [3]float64{1, 2, 3},
+		Missing: "default",
+	}
+
+	if !reflect.DeepEqual(test, want) {
+		t.Errorf("\nwant %+v\n got %+v", want, test)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	var test testUnmarshal
+	if err := Unmarshal(&Config{}, test); err == nil {
+		t.Error("unmarshal to non pointer must be error")
+	}
+
+	err := Unmarshal(FromMap(map[string]interface{}{"limits": map[string]interface{}{"small": 300}}), &test)
+	if typeErr, ok := err.(*UnmarshalTypeError); !ok || typeErr.Key != "limits.small" {
+		t.Errorf("want type error of limits.small, get %v", err)
+	}
+
+	err = Unmarshal(FromMap(map[string]interface{}{"servers": []interface{}{map[string]interface{}{"host": "a", "port": "x"}}}), &test)
+	if typeErr, ok := err.(*UnmarshalTypeError); !ok || typeErr.Key != "servers.0.port" {
+		t.Errorf("want type error of servers.0.port, get %v", err)
+	}
+
+	for _, conf := range []string{"http.port = 9223372036854775807", "http.port = 1e30", "servers = [{port = 1e30}]"} {
+		c, err := Read(strings.NewReader(conf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Unmarshal(c, &test)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("unmarshal %q, want type error, get %v", conf, err)
+		}
+	}
+
+	err = Unmarshal(FromMap(map[string]interface{}{"level": "fatal"}), &test)
+	if err == nil || !strings.Contains(err.Error(), `key "level"`) || !strings.Contains(err.Error(), "unknown level fatal") {
+		t.Errorf("want text unmarshal error of level, get %v", err)
+	}
+}
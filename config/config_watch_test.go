@@ -0,0 +1,103 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeWatchFile replaces fileName by rename, so the watcher never sees
+// a partly written file.
+func writeWatchFile(t *testing.T, fileName, content string, modTime time.Time) {
+	tmp := fileName + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, fileName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "app.conf")
+	db := filepath.Join(dir, "db.conf")
+	now := time.Now()
+	writeWatchFile(t, root, "name = app\ndb {\n\tinclude \"db.conf\"\n}\n", now)
+	writeWatchFile(t, db, "host = db1\nport = 5432\n", now)
+
+	type change struct {
+		conf    *Config
+		changed []string
+	}
+	changes := make(chan change, 10)
+	loader := &Loader{WatchInterval: 10 * time.Millisecond}
+	w, err := loader.Watch(root, func(conf *Config, changed []string) {
+		changes <- change{conf, changed}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	wait := func() change {
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("wait change timeout")
+		}
+		return change{}
+	}
+
+	// change of included file
+	writeWatchFile(t, db, "host = db2\nport = 5432\npool { size = 5 }\n", now.Add(time.Second))
+	c := wait()
+	if want := []string{"db.host", "db.pool.size"}; !reflect.DeepEqual(c.changed, want) {
+		t.Errorf("changed keys want %v get %v", want, c.changed)
+	}
+	if get := c.conf.StringDefault("db.host", ""); get != "db2" {
+		t.Errorf("get db.host want db2 get %s", get)
+	}
+
+	// parse error keeps last good config
+	writeWatchFile(t, root, "name = app\n}\n", now.Add(2*time.Second))
+	for i := 0; i < 500 && w.Err() == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if w.Err() == nil {
+		t.Fatal("want reload error")
+	}
+	if get := w.Config().StringDefault("db.host", ""); get != "db2" {
+		t.Errorf("get db.host of last good config want db2 get %s", get)
+	}
+
+	// fixed
+	writeWatchFile(t, root, "name = app2\ndb {\n\tinclude \"db.conf\"\n}\n", now.Add(3*time.Second))
+	c = wait()
+	if want := []string{"name"}; !reflect.DeepEqual(c.changed, want) {
+		t.Errorf("changed keys want %v get %v", want, c.changed)
+	}
+	if w.Err() != nil {
+		t.Errorf("want no reload error get %v", w.Err())
+	}
+	if get := w.Snapshot().StringDefault("name", ""); get != "app2" {
+		t.Errorf("get name of snapshot want app2 get %s", get)
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	a := FromMap(map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 2, "d": 3}, "e": "x", "f": []interface{}{1}})
+	b := FromMap(map[string]interface{}{"a": 1, "b": 4, "e": map[string]interface{}{"g": true}, "f": []interface{}{2}, "h": nil})
+	want := []string{"b", "b.c", "b.d", "e", "e.g", "f", "h"}
+	if get := changedKeys(a, b); !reflect.DeepEqual(get, want) {
+		t.Errorf("changed keys want %v get %v", want, get)
+	}
+}
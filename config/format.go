@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Fprint writes the syntax tree nodes parsed by Parse to w in the
+// canonical style: objects are indented by tabs, fields are written as
+// `key = value`, `key += value` or `key {`, and comments, includes and
+// single blank lines between nodes are kept.
+func Fprint(w io.Writer, nodes []*Node) error {
+	f := formatter{w: bufio.NewWriter(w)}
+	f.nodes(nodes, 0)
+	return f.w.Flush()
+}
+
+// FormatSource formats the HOCON document src, see Fprint.
+func FormatSource(src []byte) ([]byte, error) {
+	nodes, err := Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = Fprint(&buf, nodes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type formatter struct {
+	w *bufio.Writer
+}
+
+func (f *formatter) indent(depth int) {
+	f.w.WriteString(strings.Repeat("\t", depth))
+}
+
+// endLine returns the last line of node in the source, the closing brace
+// of an object is assumed to be in the line after its last field.
+func endLine(node *Node) int {
+	line := node.Line + strings.Count(node.Value, "\n")
+	if len(node.Object) > 0 {
+		if end := endLine(node.Object[len(node.Object)-1]) + 1; end > line {
+			line = end
+		}
+	}
+	return line
+}
+
+func (f *formatter) nodes(nodes []*Node, depth int) {
+	for i, node := range nodes {
+		if i > 0 {
+			prev := nodes[i-1]
+			// a comment in the line of the previous node
+			if node.Kind == CommentNode && node.Line == endLine(prev) && prev.Kind != CommentNode {
+				f.w.WriteString(" # " + node.Value)
+				continue
+			}
+
+			f.w.WriteByte('\n')
+			if node.Line > endLine(prev)+1 {
+				f.w.WriteByte('\n')
+			}
+		}
+
+		f.indent(depth)
+		switch node.Kind {
+		case CommentNode:
+			f.w.WriteString("# " + node.Value)
+		case IncludeNode:
+			f.w.WriteString("include " + node.Value)
+		case FieldNode:
+			f.field(node, depth)
+		}
+	}
+
+	if depth == 0 && len(nodes) > 0 {
+		f.w.WriteByte('\n')
+	}
+}
+
+func (f *formatter) field(node *Node, depth int) {
+	f.w.WriteString(node.Key)
+
+	if node.Object != nil && len(node.Value) == 0 && !node.Append {
+		f.w.WriteByte(' ')
+	} else {
+		if node.Append {
+			f.w.WriteString(" += ")
+		} else {
+			f.w.WriteString(" = ")
+		}
+
+		switch {
+		case len(node.Value) > 0:
+			f.value(node.Value, depth)
+		case node.Object == nil:
+			f.w.WriteString("null")
+		}
+		if node.Object == nil {
+			return
+		}
+		if len(node.Value) > 0 {
+			f.w.WriteByte(' ')
+		}
+	}
+
+	if len(node.Object) == 0 {
+		f.w.WriteString("{}")
+		return
+	}
+
+	f.w.WriteString("{\n")
+	f.nodes(node.Object, depth+1)
+	f.w.WriteByte('\n')
+	f.indent(depth)
+	f.w.WriteByte('}')
+}
+
+// value writes the source text of a value, the lines of a multi-line
+// array or object are indented by the depth of their brackets.
+func (f *formatter) value(value string, depth int) {
+	lines := strings.Split(value, "\n")
+	f.w.WriteString(strings.TrimSpace(lines[0]))
+
+	level := bracketLevel(lines[0])
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		closers := len(line) - len(strings.TrimLeft(line, "]}"))
+
+		f.w.WriteByte('\n')
+		if len(line) > 0 {
+			if n := depth + level - closers; n > 0 {
+				f.indent(n)
+			}
+			f.w.WriteString(line)
+		}
+		level += bracketLevel(line)
+	}
+}
+
+// bracketLevel returns the number of open brackets minus the number of
+// close brackets of line, skipping quoted strings and comments.
+func bracketLevel(line string) (level int) {
+	inQuote := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inQuote && c == '\\':
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '#':
+			return
+		case c == '[' || c == '{':
+			level++
+		case c == ']' || c == '}':
+			level--
+		}
+	}
+	return
+}
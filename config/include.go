@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An includeSpec is the parsed argument of an include statement, such as
+// required(file("app.conf")).
+type includeSpec struct {
+	kind     string // "file", "url", "classpath", or empty for a plain include
+	name     string
+	required bool
+}
+
+// parseInclude parses the argument of an include statement.
+func parseInclude(arg string) (spec includeSpec, err error) {
+	arg = strings.TrimSpace(arg)
+	if inner, ok := includeCall(arg, "required"); ok {
+		spec.required = true
+		arg = inner
+	}
+
+	for _, kind := range []string{"file", "url", "classpath"} {
+		if inner, ok := includeCall(arg, kind); ok {
+			spec.kind = kind
+			arg = inner
+			break
+		}
+	}
+
+	if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+		return spec, errors.New("invalid include " + strconv.Quote(arg) + ", expected a quoted string")
+	}
+	name, ok := stringBytes([]byte(arg[1 : len(arg)-1]))
+	if !ok || len(name) == 0 {
+		return spec, errors.New("invalid include " + arg)
+	}
+	spec.name = string(name)
+	return
+}
+
+// includeCall returns the argument of call name(...) in arg.
+func includeCall(arg, name string) (string, bool) {
+	if !strings.HasPrefix(arg, name) {
+		return "", false
+	}
+	rest := strings.TrimSpace(arg[len(name):])
+	if len(rest) < 2 || rest[0] != '(' || rest[len(rest)-1] != ')' {
+		return "", false
+	}
+	return strings.TrimSpace(rest[1 : len(rest)-1]), true
+}
+
+func isGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// loadInclude loads the files of spec. Missing files are skipped, unless
+// spec is required.
+func (s *fileScanner) loadInclude(spec includeSpec) (scans []*fileScanner, err error) {
+	kind := spec.kind
+	if len(kind) == 0 {
+		switch {
+		case s.url != nil || strings.Contains(spec.name, "://"):
+			kind = "url"
+		case s.fsys != nil:
+			return s.loadFSInclude(s.fsys, path.Join(s.dir, spec.name), spec.required)
+		default:
+			kind = "file"
+		}
+	}
+
+	switch kind {
+	case "url":
+		return s.loadURLInclude(spec.name, spec.required)
+	case "classpath":
+		resources := s.loader.resources()
+		if resources == nil {
+			return nil, s.includeError(spec.name, errors.New("Loader.Resources is not set"))
+		}
+		return s.loadFSInclude(resources, path.Clean(strings.TrimPrefix(spec.name, "/")), spec.required)
+	}
+	return s.loadFileInclude(spec.name, spec.required)
+}
+
+func (s *fileScanner) includeError(include string, err error) error {
+	return &ConfigError{s.name, s.bufPos.line, s.bufPos.column, include, err}
+}
+
+func (s *fileScanner) loadFileInclude(fileName string, required bool) ([]*fileScanner, error) {
+	name := fileName
+	if !filepath.IsAbs(fileName) {
+		if s.fsys == nil && s.url == nil {
+			name = filepath.Join(filepath.Dir(s.name), fileName)
+			fileName = filepath.Join(s.dir, fileName)
+		}
+		abs, err := filepath.Abs(fileName)
+		if err != nil {
+			return nil, s.includeError(name, err)
+		}
+		fileName = abs
+	}
+
+	fileNames := []string{fileName}
+	if isGlob(fileName) {
+		matches, err := filepath.Glob(fileName)
+		if err != nil {
+			return nil, s.includeError(name, err)
+		}
+		if len(matches) == 0 && required {
+			return nil, s.includeError(name, errors.New("no file matches"))
+		}
+		sort.Strings(matches)
+		fileNames = matches
+	}
+
+	scans := []*fileScanner{}
+	for _, fileName := range fileNames {
+		scanName := name
+		if isGlob(name) {
+			scanName = filepath.Join(filepath.Dir(name), filepath.Base(fileName))
+		}
+
+		if _, err := os.Stat(fileName); os.IsNotExist(err) && !required {
+			continue
+		}
+
+		scan := &fileScanner{name: scanName, loader: s.loader, includes: s.chain()}
+		err := scan.checkValid(fileName)
+		s.files = append(s.files, scan.files...)
+		if err != nil {
+			return nil, s.includeError(scanName, err)
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
+
+func (s *fileScanner) loadFSInclude(fsys fs.FS, name string, required bool) ([]*fileScanner, error) {
+	names := []string{name}
+	if isGlob(name) {
+		matches, err := fs.Glob(fsys, name)
+		if err != nil {
+			return nil, s.includeError(name, err)
+		}
+		if len(matches) == 0 && required {
+			return nil, s.includeError(name, errors.New("no file matches"))
+		}
+		names = matches
+	}
+
+	scans := []*fileScanner{}
+	for _, name := range names {
+		if _, err := fs.Stat(fsys, name); errors.Is(err, fs.ErrNotExist) && !required {
+			continue
+		}
+
+		scan := &fileScanner{loader: s.loader, includes: s.chain()}
+		if err := scan.checkFSValid(fsys, name); err != nil {
+			return nil, s.includeError(name, err)
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
+
+// chain returns the include chain of the files included by s, which does
+// not share the array of s.includes.
+func (s *fileScanner) chain() []string {
+	return s.includes[:len(s.includes):len(s.includes)]
+}
+
+func (s *fileScanner) loadURLInclude(rawURL string, required bool) ([]*fileScanner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, s.includeError(rawURL, err)
+	}
+	if s.url != nil {
+		u = s.url.ResolveReference(u)
+	}
+
+	if u.Scheme == "file" {
+		return s.loadFileInclude(u.Path, required)
+	}
+
+	resp, err := s.loader.httpClient().Get(u.String())
+	if err != nil {
+		return nil, s.includeError(u.String(), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && !required:
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, s.includeError(u.String(), errors.New(resp.Status))
+	}
+
+	scan := &fileScanner{name: u.String(), url: u, loader: s.loader}
+	if err = scan.checkReaderValid(resp.Body); err != nil {
+		return nil, s.includeError(u.String(), err)
+	}
+	return []*fileScanner{scan}, nil
+}
+
+// checkFSValid verifies that the file name in fsys is valid HOCON-encoded data.
+func (s *fileScanner) checkFSValid(fsys fs.FS, name string) error {
+	if err := s.enter(name); err != nil {
+		return err
+	}
+	s.fsys = fsys
+	s.file = path.Base(name)
+	s.dir = path.Dir(name)
+	if len(s.name) == 0 {
+		s.name = name
+	}
+
+	reader, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return s.checkReaderValid(reader)
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Layers builds a config from stacked sources, a later layer overrides
+// the values of the earlier layers. Errors of the sources are reported
+// by Build.
+//
+//	layers := config.NewLayers().
+//		File("reference.conf").
+//		File("application.conf").
+//		OptionalFile("production.conf").
+//		Args(os.Args[1:])
+//	conf, err := layers.Build()
+//	origin, _ := layers.Origin("server.port")
+type Layers struct {
+	layers  []layer
+	origins map[string]string // key path of leaf value to layer name
+	loader  *Loader
+	err     error
+}
+
+type layer struct {
+	name     string
+	conf     *Config
+	kvs      []kvPair               // fields of a file or an override, set by Build
+	comments map[string]*keyComment // comments of a file
+	override bool
+}
+
+func NewLayers() *Layers {
+	return &Layers{origins: map[string]string{}}
+}
+
+// WithLoader sets the loader of the files and of the substitutions of the
+// overrides, the settings of the zero Loader are used if it is not set.
+func (l *Layers) WithLoader(loader *Loader) *Layers {
+	l.loader = loader
+	return l
+}
+
+// File adds the config loaded from fileName as a layer named fileName.
+// The substitutions of the file are resolved by Build against the merged
+// layers, so they may refer to the values of the other layers.
+func (l *Layers) File(fileName string) *Layers {
+	return l.file(fileName, false)
+}
+
+// OptionalFile is like File, but skips the file if it does not exist.
+func (l *Layers) OptionalFile(fileName string) *Layers {
+	return l.file(fileName, true)
+}
+
+func (l *Layers) file(fileName string, optional bool) *Layers {
+	if l.err != nil {
+		return l
+	}
+
+	if optional {
+		if _, err := os.Stat(fileName); os.IsNotExist(err) {
+			return l
+		}
+	}
+
+	scan := fileScanner{name: fileName, loader: l.loader}
+	if err := l.loader.scan(&scan, fileName); err != nil {
+		l.err = err
+		return l
+	}
+	l.layers = append(l.layers, layer{name: fileName, kvs: scan.kvs, comments: scan.comments})
+	return l
+}
+
+// Add adds conf as a layer named name.
+func (l *Layers) Add(name string, conf *Config) *Layers {
+	if l.err == nil && conf != nil {
+		l.layers = append(l.layers, layer{name: name, conf: conf})
+	}
+	return l
+}
+
+// Overrides adds a layer for each override, in the form of "key=value"
+// or "-Dkey=value". The override is parsed as a HOCON field, so the value
+// may be any HOCON value, such as "-Dserver.hosts=[a, b]". Substitutions
+// of the override are resolved against the merged layers, such as
+// "-Dserver.url=${server.host}:8080", a self reference refers to the
+// layers before it, such as "-Dpath=${path}:/opt/bin". The layer is named
+// by the override.
+func (l *Layers) Overrides(overrides ...string) *Layers {
+	for _, override := range overrides {
+		if l.err != nil {
+			return l
+		}
+
+		field := strings.TrimPrefix(override, "-D")
+		if !strings.ContainsAny(field, "=:{") {
+			l.err = errors.New("config: invalid override " + strconv.Quote(override) + ": expected key=value")
+			return l
+		}
+
+		scan := fileScanner{loader: l.loader}
+		if err := scan.checkReaderValid(strings.NewReader(field)); err != nil {
+			l.err = errors.New("config: invalid override " + strconv.Quote(override) + ": " + err.Error())
+			return l
+		}
+		l.layers = append(l.layers, layer{name: override, kvs: scan.kvs, override: true})
+	}
+	return l
+}
+
+// Args adds the "-Dkey=value" arguments of args as overrides, other
+// arguments are ignored.
+func (l *Layers) Args(args []string) *Layers {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-D") {
+			l.Overrides(arg)
+		}
+	}
+	return l
+}
+
+// Build merges the layers to a new config.
+func (l *Layers) Build() (*Config, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	config := &Config{}
+	l.origins = map[string]string{}
+	for _, layer := range l.layers {
+		if layer.conf != nil {
+			if err := config.Merge("", layer.conf); err != nil {
+				return nil, err
+			}
+			l.setOrigins("", layer.conf, layer.name)
+			continue
+		}
+
+		// the fields of files and overrides are resolved after all the
+		// layers are set, so a substitution refers to the last value
+		if err := setKvs(config, layer.kvs, true); err != nil {
+			if layer.override {
+				err = errors.New("config: invalid override " + strconv.Quote(layer.name) + ": " + err.Error())
+			}
+			return nil, err
+		}
+		setComments(config, layer.comments)
+		for _, kv := range layer.kvs {
+			if value, found := lookupKeys(config, kv.keys); found {
+				l.setOrigins(joinPath(kv.keys), value, layer.name)
+			}
+		}
+	}
+
+	if err := resolve(config, l.loader); err != nil {
+		return nil, err
+	}
+	l.substitutedOrigins(config)
+	return config, nil
+}
+
+// substitutedOrigins sets the origins of the values of the objects which
+// are substituted, such as `a = ${b}`, to the origin of the object, if
+// a later layer does not set them.
+func (l *Layers) substitutedOrigins(config *Config) {
+	var objects []string
+	for path := range l.origins {
+		if subConfig(config.getValue(path)) != nil {
+			objects = append(objects, path)
+		}
+	}
+
+	for _, path := range objects {
+		name := l.origins[path]
+		delete(l.origins, path)
+		l.fillOrigins(path, config.getValue(path), name)
+	}
+}
+
+// fillOrigins sets the origins of the values of path, which are not set,
+// to name.
+func (l *Layers) fillOrigins(path string, value interface{}, name string) {
+	if conf := subConfig(value); conf != nil {
+		for _, key := range conf.Keys() {
+			v, _ := conf.get(key)
+			l.fillOrigins(joinKey(path, key), v, name)
+		}
+		return
+	}
+	if _, found := l.origins[path]; !found {
+		l.origins[path] = name
+	}
+}
+
+// setOrigins sets the origin of the value of path, and of the values of
+// its objects, to name.
+func (l *Layers) setOrigins(path string, value interface{}, name string) {
+	delete(l.origins, path)
+
+	if conf := subConfig(value); conf != nil {
+		for _, key := range conf.Keys() {
+			v, _ := conf.get(key)
+			l.setOrigins(joinKey(path, key), v, name)
+		}
+		return
+	}
+
+	// a value replaces the object of the earlier layers
+	for p := range l.origins {
+		if strings.HasPrefix(p, path+".") {
+			delete(l.origins, p)
+		}
+	}
+	l.origins[path] = name
+}
+
+// Origin returns the name of the layer which the value of key comes from,
+// after Build. Key must be the key of a value which is not an object.
+func (l *Layers) Origin(key string) (name string, found bool) {
+	keys, err := ParsePath(key)
+	if err != nil {
+		return "", false
+	}
+
+	name, found = l.origins[joinPath(keys)]
+	return
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// A Loader loads configs with the settings of its fields. The settings
+// are only used by the loads of the Loader, so loaders with different
+// settings can be used at the same time. The zero Loader has the default
+// settings, which are used by Load, LoadFS, Read and Watch.
+//
+//	loader := &config.Loader{Resources: embedded, Secrets: vault}
+//	conf, err := loader.Load("app.conf")
+type Loader struct {
+	// LookupEnv is the fallback for substitutions which are not defined
+	// in the config tree, such as ${?HOME}. It is os.LookupEnv if nil,
+	// tests can supply a fake environment.
+	LookupEnv func(key string) (string, bool)
+
+	// Secrets resolves the ${secret:name} substitutions.
+	Secrets SecretResolver
+
+	// Resources is the file system of classpath("...") includes, such as
+	// the configs embedded in the binary by embed.FS.
+	Resources fs.FS
+
+	// HTTPClient is the client of url("...") includes. It is a client
+	// with a timeout of 30s if nil.
+	HTTPClient *http.Client
+
+	// WatchInterval is the interval of a Watcher to check its files. It
+	// is 1s if zero.
+	WatchInterval time.Duration
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+func (l *Loader) lookupEnv() func(key string) (string, bool) {
+	if l == nil || l.LookupEnv == nil {
+		return os.LookupEnv
+	}
+	return l.LookupEnv
+}
+
+func (l *Loader) secrets() SecretResolver {
+	if l == nil {
+		return nil
+	}
+	return l.Secrets
+}
+
+func (l *Loader) resources() fs.FS {
+	if l == nil {
+		return nil
+	}
+	return l.Resources
+}
+
+func (l *Loader) httpClient() *http.Client {
+	if l == nil || l.HTTPClient == nil {
+		return defaultHTTPClient
+	}
+	return l.HTTPClient
+}
+
+func (l *Loader) watchInterval() time.Duration {
+	if l == nil || l.WatchInterval <= 0 {
+		return time.Second
+	}
+	return l.WatchInterval
+}
+
+// Load loads the config of the file fileName.
+func Load(fileName string) (*Config, error) {
+	return (&Loader{}).Load(fileName)
+}
+
+// LoadFS loads the config of file name in fsys, see Loader.LoadFS.
+func LoadFS(fsys fs.FS, name string) (*Config, error) {
+	return (&Loader{}).LoadFS(fsys, name)
+}
+
+// Read reads the config of reader.
+func Read(reader io.Reader) (*Config, error) {
+	return (&Loader{}).Read(reader)
+}
+
+// Load loads the config of the file fileName.
+func (l *Loader) Load(fileName string) (config *Config, err error) {
+	config, _, err = l.load(fileName)
+	return
+}
+
+// load loads the config of fileName, files are the absolute paths of
+// fileName and the files it includes, also when there is an error.
+func (l *Loader) load(fileName string) (config *Config, files []string, err error) {
+	scan := fileScanner{name: fileName, loader: l}
+	defer func() {
+		files = scan.files
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+		if err != nil {
+			config = nil
+		}
+	}()
+
+	config = &Config{}
+
+	err = l.scan(&scan, fileName)
+	if err != nil {
+		return
+	}
+
+	err = scan.setOptions(config)
+	return
+}
+
+// scan scans the file fileName and the files it includes to the fields of
+// scan, without setting them to a config.
+func (l *Loader) scan(scan *fileScanner, fileName string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
+	if !filepath.IsAbs(fileName) {
+		fileName, err = filepath.Abs(fileName)
+		if err != nil {
+			return
+		}
+	}
+	return scan.checkValid(fileName)
+}
+
+// LoadFS loads the config of file name in fsys, such as an embed.FS.
+// Plain includes are resolved relative to the including file in fsys.
+func (l *Loader) LoadFS(fsys fs.FS, name string) (config *Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+		if err != nil {
+			config = nil
+		}
+	}()
+
+	config = &Config{}
+	scan := fileScanner{loader: l}
+
+	err = scan.checkFSValid(fsys, name)
+	if err != nil {
+		return
+	}
+
+	err = scan.setOptions(config)
+	return
+}
+
+// Read reads the config of reader.
+func (l *Loader) Read(reader io.Reader) (config *Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+		if err != nil {
+			config = nil
+		}
+	}()
+
+	config = &Config{}
+	scan := fileScanner{loader: l}
+
+	err = scan.checkReaderValid(reader)
+	if err != nil {
+		return
+	}
+
+	err = scan.setOptions(config)
+	return
+}
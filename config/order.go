@@ -0,0 +1,141 @@
+package config
+
+import (
+	"sort"
+
+	"github.com/tbud/x/container/linkedmap"
+)
+
+// A Config keeps the keys of an object in the order they are set, which is
+// the declaration order for configs created by Load or Read, and the
+// comments of the keys read from a file.
+
+type keyComment struct {
+	leading  []string // comment lines before the key
+	trailing string   // comment after the value, in the same line
+}
+
+// get returns the value of key of c.
+func (c *Config) get(key string) (value interface{}, found bool) {
+	if c == nil {
+		return nil, false
+	}
+	if c.values == nil {
+		return nil, false
+	}
+	return c.values.Get(key)
+}
+
+// set sets the value of key of c, a new key is added after the keys of c.
+func (c *Config) set(key string, value interface{}) {
+	if c.values == nil {
+		c.values = linkedmap.New()
+	}
+	c.values.Append(key, value)
+}
+
+// remove removes key and its comment from c.
+func (c *Config) remove(key string) {
+	if c.values == nil || !c.values.Remove(key) {
+		return
+	}
+	delete(c.comments, key)
+}
+
+// setComment sets the comment of key of c.
+func (c *Config) setComment(key string, comment *keyComment) {
+	if c.comments == nil {
+		c.comments = map[string]*keyComment{}
+	}
+	c.comments[key] = comment
+}
+
+// comment returns the comment of key of c, or nil.
+func (c *Config) comment(key string) *keyComment {
+	if c == nil {
+		return nil
+	}
+	return c.comments[key]
+}
+
+// orderedKeys returns the keys of c. When keepOrder is true, the keys are
+// in the order they are set, else they are sorted.
+func (c *Config) orderedKeys(keepOrder bool) []string {
+	if c == nil || c.values == nil {
+		return nil
+	}
+	keys := make([]string, 0, c.values.Len())
+	for _, key := range c.values.Keys() {
+		keys = append(keys, key.(string))
+	}
+	if !keepOrder {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+// FromMap returns the config of m. The objects of m, and of the arrays in
+// it, are converted to configs too, their keys are sorted.
+func FromMap(m map[string]interface{}) *Config {
+	if m == nil {
+		return nil
+	}
+	return configValue(m).(*Config)
+}
+
+// configValue returns value with its maps converted to configs.
+func configValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		conf := &Config{}
+		for _, key := range keys {
+			conf.set(key, configValue(v[key]))
+		}
+		return conf
+	case []interface{}:
+		elems := make([]interface{}, len(v))
+		for i, elem := range v {
+			elems[i] = configValue(elem)
+		}
+		return elems
+	}
+	return value
+}
+
+// Map returns the values of c as a map, the objects of c, and of the
+// arrays in it, are maps too.
+func (c *Config) Map() map[string]interface{} {
+	if c == nil {
+		return nil
+	}
+	return mapValue(c).(map[string]interface{})
+}
+
+// mapValue returns value with its configs converted to maps.
+func mapValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *Config:
+		if v == nil {
+			return nil
+		}
+		m := make(map[string]interface{}, v.KeyLen())
+		for _, key := range v.Keys() {
+			value, _ := v.get(key)
+			m[key] = mapValue(value)
+		}
+		return m
+	case []interface{}:
+		elems := make([]interface{}, len(v))
+		for i, elem := range v {
+			elems[i] = mapValue(elem)
+		}
+		return elems
+	}
+	return value
+}
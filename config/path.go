@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ParsePath parses a HOCON path expression to its keys. Keys are separated
+// by '.', a quoted key may contain '.', such as `hosts."a.b.com".port`,
+// and quoted and unquoted parts of a key are concatenated. A key of
+// digits is an index when the value it applies to is an array, such as
+// servers.0.host.
+func ParsePath(path string) (keys []string, err error) {
+	invalid := func(msg string) error {
+		return errors.New("config: invalid path " + strconv.Quote(path) + ": " + msg)
+	}
+
+	key := []byte{}
+	quoted := false // the key has a quoted part, so it may be empty
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '.':
+			if len(key) == 0 && !quoted {
+				return nil, invalid("empty key")
+			}
+			keys = append(keys, string(key))
+			key, quoted = []byte{}, false
+		case '"':
+			end := i + 1
+			for ; end < len(path) && path[end] != '"'; end++ {
+				if path[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(path) {
+				return nil, invalid("unterminated quoted key")
+			}
+			b, ok := stringBytes([]byte(path[i+1 : end]))
+			if !ok {
+				return nil, invalid("invalid quoted key")
+			}
+			key = append(key, b...)
+			quoted = true
+			i = end
+		default:
+			key = append(key, c)
+		}
+	}
+
+	if len(key) == 0 && !quoted {
+		return nil, invalid("empty key")
+	}
+	return append(keys, string(key)), nil
+}
+
+// pathIndex returns the array index of key, ok is false if key is not
+// an index.
+func pathIndex(key string) (index int, ok bool) {
+	if len(key) == 0 || len(key) > 1 && key[0] == '0' {
+		return 0, false
+	}
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(key)
+	return index, err == nil
+}
+
+// quoteKey returns key quoted if it is not a simple key of a path.
+func quoteKey(key string) string {
+	if len(key) == 0 || strings.ContainsAny(key, ".\"\\") || strings.TrimSpace(key) != key {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+// joinKey joins the path of an object and a key of it to a path,
+// which can be parsed by ParsePath.
+func joinKey(path, key string) string {
+	if len(path) == 0 {
+		return quoteKey(key)
+	}
+	return path + "." + quoteKey(key)
+}
+
+// joinPath joins keys to a path, which can be parsed by ParsePath.
+func joinPath(keys []string) string {
+	path := ""
+	for _, key := range keys {
+		path = joinKey(path, key)
+	}
+	return path
+}
+
+// lookupKeys returns the value of the path keys in value.
+func lookupKeys(value interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		if elems, ok := value.([]interface{}); ok {
+			index, ok := pathIndex(key)
+			if !ok || index >= len(elems) {
+				return nil, false
+			}
+			value = elems[index]
+			continue
+		}
+
+		object := subConfig(value)
+		if object == nil {
+			return nil, false
+		}
+		var found bool
+		if value, found = object.get(key); !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// mergeKeys merges value to the path keys in dst, and returns the merged
+// value of dst. Objects are merged, other values replace the values of dst.
+func mergeKeys(dst interface{}, keys []string, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		src := subConfig(value)
+		if src == nil {
+			return value, nil
+		}
+
+		object := subConfig(dst)
+		if object == nil {
+			object = &Config{}
+		}
+		for _, key := range src.Keys() {
+			d, _ := object.get(key)
+			s, _ := src.get(key)
+			v, err := mergeKeys(d, nil, s)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key, v)
+		}
+		return object, nil
+	}
+
+	key := keys[0]
+	if elems, ok := dst.([]interface{}); ok {
+		if index, ok := pathIndex(key); ok {
+			if index >= len(elems) {
+				return nil, errors.New("config: index " + key + " out of range of array with length " + strconv.Itoa(len(elems)))
+			}
+			v, err := mergeKeys(elems[index], keys[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			elems[index] = v
+			return elems, nil
+		}
+	}
+
+	object := subConfig(dst)
+	if object == nil {
+		object = &Config{}
+	}
+	d, _ := object.get(key)
+	v, err := mergeKeys(d, keys[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	object.set(key, v)
+	return object, nil
+}
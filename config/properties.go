@@ -0,0 +1,314 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadProperties loads the config of the Java properties file fileName,
+// see ReadProperties.
+func LoadProperties(fileName string) (*Config, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config, err := ReadProperties(f)
+	if synErr, ok := err.(*SyntaxError); ok {
+		synErr.File = fileName
+	}
+	return config, err
+}
+
+// ReadProperties reads a config from the Java properties of reader.
+// Keys are split on '.' to the path of their value, values are strings,
+// which are converted by Unmarshal and Slice, except the value [] which is
+// an empty array. Properties such as foo.0 and foo.1 are an array for
+// Slice. If a key is both a value and an object, such as a=1 and a.b=2,
+// the object wins. Values with the secret: prefix are secrets, as in HOCON.
+func ReadProperties(reader io.Reader) (*Config, error) {
+	config := &Config{}
+	lines := bufio.NewScanner(reader)
+	lineNum := 0
+	for lines.Scan() {
+		lineNum++
+		line := strings.TrimLeft(lines.Text(), " \t\f")
+		if len(line) == 0 || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		start := lineNum
+		// a line ending with an odd number of backslashes is continued
+		for continued(line) && lines.Scan() {
+			lineNum++
+			line = line[:len(line)-1] + strings.TrimLeft(lines.Text(), " \t\f")
+		}
+		if continued(line) {
+			line = line[:len(line)-1]
+		}
+
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return nil, &SyntaxError{msg: err.Error(), Line: start, Column: 1}
+		}
+		setProperty(config, strings.Split(key, "."), value)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	markSecrets(config)
+	return config, nil
+}
+
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, "\\"))
+	return n%2 == 1
+}
+
+// splitProperty splits the logical line of a property to its unescaped
+// key and value, the value [] is an empty array.
+func splitProperty(line string) (key string, value interface{}, err error) {
+	end := 0
+	for ; end < len(line); end++ {
+		c := line[end]
+		if c == '\\' {
+			end++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if len(rest) > 0 && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	if key, err = unescapeProperty(line[:end]); err != nil {
+		return
+	}
+	if rest == "[]" {
+		return key, []interface{}{}, nil
+	}
+	value, err = unescapeProperty(rest)
+	return
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c = s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", errors.New("malformed \\uxxxx escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", errors.New("malformed \\uxxxx escape")
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// setProperty sets value to the path keys in config, an object is not
+// replaced by a value.
+func setProperty(config *Config, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		previous, _ := config.get(key)
+		object := subConfig(previous)
+		if object == nil {
+			object = &Config{}
+			config.set(key, object)
+		}
+		config = object
+	}
+
+	key := keys[len(keys)-1]
+	if previous, _ := config.get(key); subConfig(previous) == nil {
+		config.set(key, value)
+	}
+}
+
+// numericArray converts an object with the keys 0 to n-1, such as the
+// object of properties foo.0 and foo.1, to an array, ordered by the keys.
+// It returns an error if the object has a key which is not an index, or if
+// an index is missing.
+func numericArray(object *Config) ([]interface{}, error) {
+	if object.KeyLen() == 0 {
+		return nil, errors.New("object is not an array")
+	}
+
+	elems := make([]interface{}, object.KeyLen())
+	for _, key := range object.Keys() {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(elems) || strconv.Itoa(index) != key {
+			return nil, errors.New("key " + strconv.Quote(key) + " is not an array index")
+		}
+		elems[index], _ = object.get(key)
+	}
+	return elems, nil
+}
+
+// Flatten returns the values of c by their paths, such as a.b for the
+// value of b in the object a. Keys are quoted if needed, see ParsePath.
+// Arrays and empty objects are values, so Unflatten returns c again.
+func (c *Config) Flatten() map[string]interface{} {
+	flat := map[string]interface{}{}
+	flatten(flat, "", c)
+	return flat
+}
+
+func flatten(flat map[string]interface{}, path string, object *Config) {
+	for _, key := range object.Keys() {
+		value, _ := object.get(key)
+		if sub := subConfig(value); sub.KeyLen() > 0 {
+			flatten(flat, joinKey(path, key), sub)
+		} else {
+			flat[joinKey(path, key)] = value
+		}
+	}
+}
+
+// Unflatten returns the config of the values of paths, such as returned by
+// Flatten. If a path is both a value and an object, the object wins.
+func Unflatten(flat map[string]interface{}) (*Config, error) {
+	paths := make([]string, 0, len(flat))
+	for path := range flat {
+		paths = append(paths, path)
+	}
+	// parent paths are set before the paths of their objects
+	sort.Strings(paths)
+
+	var config interface{} = &Config{}
+	for _, path := range paths {
+		keys, err := ParsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		if config, err = mergeKeys(config, keys, configValue(flat[path])); err != nil {
+			return nil, err
+		}
+	}
+	return config.(*Config), nil
+}
+
+// properties writes the values of object as Java properties, arrays are
+// written as properties of their indexes.
+func (r *renderer) properties(path string, value interface{}) {
+	if object := subConfig(value); object != nil {
+		for _, key := range object.orderedKeys(r.opts.KeepOrder) {
+			if strings.Contains(key, ".") && r.err == nil {
+				r.err = errors.New("config: key " + strconv.Quote(key) + " contains '.', it can not be a property")
+			}
+			value, _ := object.get(key)
+			if len(path) > 0 {
+				r.properties(path+"."+key, value)
+			} else {
+				r.properties(key, value)
+			}
+		}
+		return
+	}
+
+	if elems, ok := elements(value); ok {
+		if len(elems) == 0 {
+			r.w.WriteString(escapeProperty(path, true))
+			r.w.WriteString("=[]\n")
+			return
+		}
+		for i, elem := range elems {
+			r.properties(path+"."+strconv.Itoa(i), elem)
+		}
+		return
+	}
+
+	r.w.WriteString(escapeProperty(path, true))
+	r.w.WriteByte('=')
+	if s := propertyValue(value); s == "[]" {
+		// not an empty array
+		r.w.WriteString(`\[]`)
+	} else {
+		r.w.WriteString(escapeProperty(s, false))
+	}
+	r.w.WriteByte('\n')
+}
+
+func propertyValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case Secret:
+		s, _ := secretForm(v)
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// escapeProperty escapes s as a key or a value of a Java property.
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case ' ':
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/tbud/x/encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Format is the output format of Render. A Secret is written as *** in
+// JSON, and in the secret: form, such as "secret:hunter2", in the other
+// formats.
+type Format int
+
+const (
+	FormatJSON       Format = iota // compact JSON
+	FormatPrettyJSON               // indented JSON
+	FormatHOCON                    // HOCON, with root braces omitted
+	FormatProperties               // Java properties, see ReadProperties
+	FormatYAML                     // YAML block mappings and sequences, for output only
+)
+
+// RenderOptions are the options of Render.
+type RenderOptions struct {
+	Format    Format
+	Indent    string // indent of pretty JSON and HOCON, "\t" if empty, YAML is indented by two spaces
+	KeepOrder bool   // keep the key order of the parsed file, else keys are sorted
+	Comments  bool   // write the comments of the parsed file, only for HOCON
+}
+
+// Render writes conf to w in the format of opts. If opts is nil,
+// conf is written as compact JSON with sorted keys.
+//
+// The comments are only known for configs created by Load or Read.
+func Render(w io.Writer, conf *Config, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+
+	r := renderer{w: bufio.NewWriter(w), opts: *opts}
+	if len(r.opts.Indent) == 0 {
+		r.opts.Indent = "\t"
+	}
+
+	switch r.opts.Format {
+	case FormatHOCON:
+		r.hoconFields(conf, 0)
+	case FormatProperties:
+		r.properties("", conf)
+	case FormatYAML:
+		r.yamlValue(conf, 0)
+		r.w.WriteByte('\n')
+	default:
+		r.jsonValue(conf, 0)
+		if r.opts.Format == FormatPrettyJSON {
+			r.w.WriteByte('\n')
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+	return r.w.Flush()
+}
+
+type renderer struct {
+	w    *bufio.Writer
+	opts RenderOptions
+	err  error
+}
+
+func (r *renderer) newline(depth int) {
+	r.w.WriteByte('\n')
+	r.w.WriteString(strings.Repeat(r.opts.Indent, depth))
+}
+
+func (r *renderer) scalar(value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	r.w.Write(b)
+}
+
+// elements returns the elements of value, found is false if value is
+// not an array.
+func elements(value interface{}) (elems []interface{}, found bool) {
+	if elems, found = value.([]interface{}); found {
+		return
+	}
+
+	rv := reflect.ValueOf(value)
+	if value == nil || rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems = make([]interface{}, rv.Len())
+	for i := range elems {
+		elems[i] = rv.Index(i).Interface()
+	}
+	return elems, true
+}
+
+func (r *renderer) jsonValue(value interface{}, depth int) {
+	pretty := r.opts.Format == FormatPrettyJSON
+
+	if object := subConfig(value); object != nil {
+		keys := object.orderedKeys(r.opts.KeepOrder)
+		if len(keys) == 0 {
+			r.w.WriteString("{}")
+			return
+		}
+
+		r.w.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				r.w.WriteByte(',')
+			}
+			if pretty {
+				r.newline(depth + 1)
+			}
+			r.scalar(key)
+			r.w.WriteByte(':')
+			if pretty {
+				r.w.WriteByte(' ')
+			}
+			value, _ := object.get(key)
+			r.jsonValue(value, depth+1)
+		}
+		if pretty {
+			r.newline(depth)
+		}
+		r.w.WriteByte('}')
+		return
+	}
+
+	if elems, ok := elements(value); ok {
+		if len(elems) == 0 {
+			r.w.WriteString("[]")
+			return
+		}
+
+		r.w.WriteByte('[')
+		for i, elem := range elems {
+			if i > 0 {
+				r.w.WriteByte(',')
+			}
+			if pretty {
+				r.newline(depth + 1)
+			}
+			r.jsonValue(elem, depth+1)
+		}
+		if pretty {
+			r.newline(depth)
+		}
+		r.w.WriteByte(']')
+		return
+	}
+
+	r.scalar(value)
+}
+
+// hoconKey returns key unquoted if it is a simple word, else quoted.
+func hoconKey(key string) string {
+	simple := len(key) > 0
+	for i, c := range key {
+		if !(unicode.IsLetter(c) || c == '_' || i > 0 && (unicode.IsDigit(c) || c == '-')) {
+			simple = false
+			break
+		}
+	}
+
+	switch key {
+	case "true", "false", "null", Include_Keyword:
+		simple = false
+	}
+
+	if simple {
+		return key
+	}
+	b, _ := json.Marshal(key)
+	return string(b)
+}
+
+func (r *renderer) hoconFields(object *Config, depth int) {
+	for i, key := range object.orderedKeys(r.opts.KeepOrder) {
+		var comment *keyComment
+		if r.opts.Comments {
+			comment = object.comment(key)
+		}
+
+		if i > 0 || depth > 0 {
+			if i > 0 && comment != nil && len(comment.leading) > 0 {
+				r.w.WriteByte('\n')
+			}
+			r.newline(depth)
+		}
+		if comment != nil {
+			for _, line := range comment.leading {
+				r.w.WriteString("# " + line)
+				r.newline(depth)
+			}
+		}
+
+		r.w.WriteString(hoconKey(key))
+		value, _ := object.get(key)
+		if subConfig(value) != nil {
+			r.w.WriteByte(' ')
+		} else {
+			r.w.WriteString(" = ")
+		}
+		r.hoconValue(value, depth)
+
+		if comment != nil && len(comment.trailing) > 0 {
+			r.w.WriteString(" # " + comment.trailing)
+		}
+	}
+
+	if depth == 0 {
+		r.w.WriteByte('\n')
+	}
+}
+
+func (r *renderer) hoconValue(value interface{}, depth int) {
+	if object := subConfig(value); object != nil {
+		if object.KeyLen() == 0 {
+			r.w.WriteString("{}")
+			return
+		}
+
+		r.w.WriteByte('{')
+		r.hoconFields(object, depth+1)
+		r.newline(depth)
+		r.w.WriteByte('}')
+		return
+	}
+
+	if elems, ok := elements(value); ok {
+		simple := true
+		for _, elem := range elems {
+			if _, ok := elements(elem); ok || subConfig(elem) != nil {
+				simple = false
+				break
+			}
+		}
+
+		r.w.WriteByte('[')
+		for i, elem := range elems {
+			if simple {
+				if i > 0 {
+					r.w.WriteString(", ")
+				}
+			} else {
+				r.newline(depth + 1)
+			}
+			r.hoconValue(elem, depth+1)
+		}
+		if !simple && len(elems) > 0 {
+			r.newline(depth)
+		}
+		r.w.WriteByte(']')
+		return
+	}
+
+	if s, ok := secretForm(value); ok {
+		value = s
+	}
+	r.scalar(value)
+}
+
+// MarshalJSON returns c as compact JSON in the order of Keys.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Render(&buf, c, &RenderOptions{KeepOrder: true}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
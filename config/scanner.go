@@ -10,10 +10,15 @@ package config
 // before diving into the scanner itself.
 
 import (
+	"bytes"
+	"errors"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"unicode"
@@ -24,12 +29,66 @@ import (
 // A SyntaxError is a description of a HOCON syntax error.
 type SyntaxError struct {
 	msg    string // description of error
-	buf    []byte // save file scan parse buf
 	Offset int64  // error occurred after reading Offset bytes
+	File   string // file of the error, empty when read from a reader
+	Line   int    // line of the error, starting at 1, 0 if unknown
+	Column int    // column of the error, starting at 1
 }
 
 func (e *SyntaxError) Error() string {
-	return e.msg + strconv.Quote(string(e.buf)) + " Offset " + strconv.FormatInt(e.Offset, 10)
+	return position{e.File, e.Line, e.Column}.prefix() + e.msg
+}
+
+// A ConfigError is an error in a file loaded by an include statement.
+// Err is the error of the included file, which may be a ConfigError
+// again when the included file has an include statement too.
+type ConfigError struct {
+	File    string // file which has the include statement
+	Line    int    // line of the include statement
+	Column  int    // column of the include statement
+	Include string // file to be included
+	Err     error  // error of the included file
+}
+
+func (e *ConfigError) Error() string {
+	return position{e.File, e.Line, e.Column}.prefix() + "include " + strconv.Quote(e.Include) + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// IncludeChain returns the files from the root file to the innermost
+// included file where the error happened.
+func (e *ConfigError) IncludeChain() []string {
+	chain := []string{e.File}
+	for err := e; ; {
+		inner, ok := err.Err.(*ConfigError)
+		if !ok {
+			return append(chain, err.Include)
+		}
+		chain = append(chain, inner.File)
+		err = inner
+	}
+}
+
+// A position is a place in a file, to report errors.
+type position struct {
+	file   string
+	line   int
+	column int
+}
+
+func (p position) prefix() string {
+	switch {
+	case p.line == 0 && len(p.file) == 0:
+		return ""
+	case p.line == 0:
+		return p.file + ": "
+	case len(p.file) == 0:
+		return strconv.Itoa(p.line) + ":" + strconv.Itoa(p.column) + ": "
+	}
+	return p.file + ":" + strconv.Itoa(p.line) + ":" + strconv.Itoa(p.column) + ": "
 }
 
 // A scanner is a HOCON scanning state machine.
@@ -58,20 +117,60 @@ type fileScanner struct {
 	bytes int64
 
 	file         string   // file name
+	name         string   // file name to report errors
 	dir          string   // dir of the file
+	line         int      // line of the current byte
+	column       int      // column of the current byte
+	keyPos       position // position of the current key
+	bufPos       position // position of the current buf
 	data         []byte   // store data load from file
 	baseKeys     []string // save base key
 	keyStack     []int    // stack for key
 	parseBuf     []byte   // save parsed key or value
 	bufType      int      // buf type
 	bufInQuote   bool     // when buf type is no quote string and parse in quote then true
+	bufInSubst   bool     // when buf type is no quote string and parse in ${} then true
+	included     bool     // when the last key is an include then true
 	currentState int      // save current state
 	kvs          []kvPair //save key value
+	frames       []scanFrame
+	comment      []byte                 // comment being scanned
+	leading      bool                   // comment is before a key
+	commentLines []string               // comment lines before the next key
+	lastKey      string                 // key of the last value, for comment after it
+	comments     map[string]*keyComment // comments of key paths
+	files        []string               // absolute paths of the file and its includes
+	fsys         fs.FS                  // file system of the file, nil for the OS
+	url          *url.URL               // url of the file, when included by url()
+	loader       *Loader                // settings of the load, nil for the defaults
+	objectKeys   []string               // keys of the last object value, to concatenate objects
+	appending    bool                   // when the key is followed by += then true
+	tree         *treeBuilder           // syntax tree built by Parse, nil for Load
+	valueStart   int64                  // offset of the current value
+	commentPos   position               // position of the current comment
+	opens        []bracket              // objects and arrays which are not closed
+	includes     []string               // paths of the file and of the files including it
+	concatSelf   bool                   // the value is concatenated to the object or array of its key
+	objectRaw    string                 // source text of the last object, for Parse
+}
+
+// A scanFrame saves the scan state of the outer value, when an object
+// or array is scanned as an element of array.
+type scanFrame struct {
+	isArray   bool        // element is an array
+	appending bool        // element is the value of +=
+	concat    bool        // element is concatenated to base, such as [${list} [1]]
+	base      interface{} // value which the element is concatenated to
+	baseKeys  []string
+	keyStack  []int
+	keyPos    position
+	kvs       []kvPair
 }
 
 type kvPair struct {
 	keys  []string
 	value interface{} // value or filescanner
+	pos   position    // position of the key
 }
 
 // These values are returned by the state transition functions
@@ -121,6 +220,7 @@ func (s *fileScanner) init() {
 	s.bytes = 0
 	s.currentState = parseKey
 	s.bufInQuote = false
+	s.bufInSubst = false
 }
 
 // checkValid verifies that data is valid HOCON-encoded data.
@@ -130,8 +230,15 @@ func (s *fileScanner) checkValid(fileName string) (err error) {
 		return s.errorSyntax("file '" + fileName + "' is not absolute path")
 	}
 
+	if err = s.enter(fileName); err != nil {
+		return
+	}
+	s.files = append(s.files, fileName)
 	s.file = filepath.Base(fileName)
 	s.dir = filepath.Dir(fileName)
+	if len(s.name) == 0 {
+		s.name = fileName
+	}
 
 	var reader io.ReadWriteCloser
 	if reader, err = os.Open(fileName); err == nil {
@@ -142,7 +249,28 @@ func (s *fileScanner) checkValid(fileName string) (err error) {
 	return
 }
 
+// enter adds the file path to the include chain of s, it returns an
+// error if path includes itself, such as by `include "*.conf"`.
+func (s *fileScanner) enter(path string) error {
+	for _, include := range s.includes {
+		if include == path {
+			return errors.New("include cycle of " + strconv.Quote(path))
+		}
+	}
+	s.includes = append(s.includes, path)
+	return nil
+}
+
 func (s *fileScanner) checkReaderValid(reader io.Reader) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			err = r.(error)
+		}
+	}()
+
 	s.init()
 
 	s.data, err = ioutil.ReadAll(reader)
@@ -150,46 +278,154 @@ func (s *fileScanner) checkReaderValid(reader io.Reader) (err error) {
 		return s.errorSyntax(err.Error())
 	}
 
+	s.line, s.column = 1, 0
 	for _, c := range s.data {
 		s.bytes++
+		if c < utf8.RuneSelf || utf8.RuneStart(c) {
+			s.column++
+		}
+
 		switch s.step(s, int(c)) {
 		case scanError:
 			return s.err
 		case scanAppendBuf:
+			// the position of a quoted key is at its quote
+			if len(s.parseBuf) == 0 && s.bufType != bufTypeString {
+				s.bufPos = s.position()
+			}
 			s.parseBuf = append(s.parseBuf, c)
 		}
+
+		if c == '\n' {
+			s.line++
+			s.column = 0
+		}
 	}
 
 	if s.step(s, '\n') == scanError {
 		return s.err
 	}
-	return nil
+	return s.checkEnd()
+}
+
+// A bracket is the '{' of an object or the '[' of an array.
+type bracket struct {
+	c      byte
+	pos    position
+	offset int64 // offset of the bracket in s.data
+	kvs    int   // len(s.kvs) at the bracket
 }
 
-func (s *fileScanner) setOptions(config Config) error {
-	for _, kv := range s.kvs {
+// open saves the bracket c of an object or array at the current byte.
+func (s *fileScanner) open(c byte) {
+	s.opens = append(s.opens, bracket{c, s.position(), s.bytes - 1, len(s.kvs)})
+}
+
+// close removes the bracket of the object or array closed by '}' or ']'
+// and returns it.
+func (s *fileScanner) close() (open bracket) {
+	if len(s.opens) > 0 {
+		open = s.opens[len(s.opens)-1]
+		s.opens = s.opens[:len(s.opens)-1]
+	}
+	return
+}
+
+// checkEnd returns an error at the bracket which is not closed at the
+// end of the input, such as `a = {` or `a = [1, 2`.
+func (s *fileScanner) checkEnd() error {
+	if len(s.opens) == 0 {
+		return nil
+	}
+	open := s.opens[len(s.opens)-1]
+	if open.c == '[' {
+		return open.pos.errorSyntax("array is not closed by ']'")
+	}
+	return open.pos.errorSyntax("object is not closed by '}'")
+}
+
+func (s *fileScanner) setOptions(config *Config) error {
+	if err := setKvs(config, s.kvs, true); err != nil {
+		return err
+	}
+	setComments(config, s.comments)
+	return resolve(config, s.loader)
+}
+
+// setKvs sets the key values to config. When selfRef is true, the
+// self referential substitutions are replaced by the previous value.
+func setKvs(config *Config, kvs []kvPair, selfRef bool) error {
+	for _, kv := range kvs {
 		ops := config
 		for i := 0; i < len(kv.keys)-1; i++ {
 			key := kv.keys[i]
-			if optMap, ok := ops[key]; ok {
-				if v, ok := optMap.(map[string]interface{}); ok {
+			if optMap, ok := ops.get(key); ok {
+				if v, ok := optMap.(*Config); ok {
 					ops = v
+				} else if v, ok := optMap.(*merge); ok {
+					ops = v.object
+				} else if !isResolved(optMap) {
+					// an object merged onto a substitution, such as ${base} { x = 1 }
+					m := &merge{optMap, &Config{}}
+					ops.set(key, m)
+					ops = m.object
 				} else {
-					return &SyntaxError{"set config value error, key " + key + " is not a map[string]interface{}", []byte{}, int64(i)}
+					return &SyntaxError{"key " + strconv.Quote(joinPath(kv.keys[:i+1])) + " is not an object", 0, kv.pos.file, kv.pos.line, kv.pos.column}
 				}
 			} else {
-				ops[key] = map[string]interface{}{}
-				ops = ops[key].(map[string]interface{})
+				object := &Config{}
+				ops.set(key, object)
+				ops = object
 			}
 		}
 
-		ops[kv.keys[len(kv.keys)-1]] = kv.value
+		key := kv.keys[len(kv.keys)-1]
+		if object, ok := kv.value.(*Config); ok && object.KeyLen() == 0 {
+			// an empty object merged to an object keeps it
+			if previous, found := ops.get(key); found {
+				switch previous.(type) {
+				case *Config, *merge:
+					continue
+				}
+			}
+		}
+		if !selfRef || isResolved(kv.value) {
+			ops.set(key, kv.value)
+		} else {
+			previous, found := ops.get(key)
+			if value, ok := selfReference(kv.value, kv.keys, previous, found); ok {
+				value, err := resolveSelfConcat(value, kv.keys, kv.pos)
+				if err != nil {
+					return err
+				}
+				ops.set(key, value)
+			}
+		}
 	}
 	return nil
 }
 
+// setComments sets the comments of keys to the objects of config.
+func setComments(config *Config, comments map[string]*keyComment) {
+	for path, comment := range comments {
+		keys, err := ParsePath(path)
+		if err != nil {
+			continue
+		}
+		if object := subConfig(config.getValue(joinPath(keys[:len(keys)-1]))); len(keys) == 1 {
+			config.setComment(keys[0], comment)
+		} else if object != nil {
+			object.setComment(keys[len(keys)-1], comment)
+		}
+	}
+}
+
 func (s *fileScanner) pushKeyStack() {
+	if s.tree != nil && len(s.frames) == 0 {
+		s.tree.open(s.fieldKey(), s.keyPos)
+	}
 	s.keyStack = append(s.keyStack, len(s.baseKeys))
+	s.open('{')
 }
 
 func (s *fileScanner) popKeyStack() {
@@ -211,13 +447,25 @@ func (s *fileScanner) pushKey() {
 	case scanError:
 		panic(s.err)
 	case scanContinue:
+		if stackLen := len(s.keyStack); stackLen == 0 && len(s.baseKeys) == 0 ||
+			stackLen > 0 && len(s.baseKeys) == s.keyStack[stackLen-1] {
+			s.keyPos = s.bufPos
+			if len(s.commentLines) > 0 && len(s.frames) == 0 {
+				s.keyComment(joinKey(joinPath(s.baseKeys), string(s.parseBuf))).leading = s.commentLines
+			}
+			s.commentLines = nil
+		}
 		s.baseKeys = append(s.baseKeys, string(s.parseBuf))
+	case scanSkipSpace:
+		s.included = true
 	}
 	s.parseBuf = s.parseBuf[0:0]
 	s.bufInQuote = false
 }
 
 func (s *fileScanner) pushValue() {
+	included := s.included
+	s.included = false
 	if len(s.baseKeys) == 0 {
 		return
 	}
@@ -226,13 +474,38 @@ func (s *fileScanner) pushValue() {
 	case scanError:
 		panic(s.err)
 	case scanContinue:
+		if included {
+			// key is an include, which has no value
+			break
+		}
+
 		baseKeys := make([]string, len(s.baseKeys))
 		copy(baseKeys, s.baseKeys)
-		if len(s.parseBuf) == 0 {
-			s.kvs = append(s.kvs, kvPair{baseKeys, nil})
-		} else {
-			s.kvs = append(s.kvs, kvPair{baseKeys, s.parseBufValue()})
+		s.lastKey = joinPath(baseKeys)
+		if s.tree != nil && len(s.frames) == 0 {
+			if s.concatSelf {
+				s.tree.concatValue(s.objectRaw, string(s.parseBuf))
+			} else {
+				s.tree.field(s.fieldKey(), string(s.parseBuf), s.appending, s.keyPos)
+			}
 		}
+		var value interface{}
+		if len(s.parseBuf) > 0 {
+			value = s.parseBufValue()
+		}
+		if s.appending {
+			value = concatenation{s.appendBase(), []interface{}{value}}
+		}
+		if s.concatSelf {
+			// `a = [1] ${more}` is the same as `a = [1]`, `a = ${a} ${more}`
+			pieces := concatenation{&substitution{path: s.lastKey, pos: s.keyPos}}
+			if v, ok := value.(concatenation); ok {
+				value = append(pieces, v...)
+			} else {
+				value = append(pieces, value)
+			}
+		}
+		s.kvs = append(s.kvs, kvPair{baseKeys, value, s.keyPos})
 	}
 
 	// pop base key
@@ -247,6 +520,60 @@ func (s *fileScanner) pushValue() {
 	s.parseBuf = s.parseBuf[0:0]
 	s.bufType = bufTypeNull
 	s.bufInQuote = false
+	s.bufInSubst = false
+	s.appending = false
+	s.concatSelf = false
+}
+
+// appendBase returns the optional self reference of the key of +=,
+// `a += b` is the same as `a = ${?a} [b]`.
+func (s *fileScanner) appendBase() *substitution {
+	return &substitution{path: joinPath(s.baseKeys), optional: true, pos: s.keyPos}
+}
+
+// beginSelfConcat begins to scan the value c concatenated to the object
+// or array value of the key before it, such as `a = [1] ${more}` or
+// `a = { x = 1 } ${b}`.
+func (s *fileScanner) beginSelfConcat(keys []string, c int) int {
+	s.baseKeys = append(s.baseKeys, keys[len(s.baseKeys):]...)
+	s.concatSelf = true
+	s.currentState = parseValue
+	s.step = stateBeginValue
+	return stateBeginValue(s, c)
+}
+
+// beginConcat begins to scan an object or array c, which is concatenated
+// to the value in buf, such as ${base} { x = 1 } or ${list} [1, 2].
+func (s *fileScanner) beginConcat(c int) int {
+	if s.appending {
+		return s.error(c, "in value of +=")
+	}
+
+	s.trimParseBuf()
+	if s.tree != nil && len(s.frames) == 0 && c == '{' {
+		s.tree.field(s.fieldKey(), string(s.parseBuf), false, s.keyPos)
+		s.tree.concat = true
+	}
+	value := s.parseBufValue()
+	s.parseBuf = s.parseBuf[0:0]
+	s.bufType = bufTypeNull
+	s.bufInSubst = false
+
+	if c == '[' {
+		s.open('[')
+		s.pushArrayKey(concatenation{value, []interface{}{}})
+		s.step = stateBeginValue
+		s.currentState = parseArrayValue
+		return scanContinue
+	}
+
+	baseKeys := make([]string, len(s.baseKeys))
+	copy(baseKeys, s.baseKeys)
+	s.kvs = append(s.kvs, kvPair{baseKeys, value, s.keyPos})
+	s.pushKeyStack()
+	s.step = stateBeginKey
+	s.currentState = parseKey
+	return scanContinue
 }
 
 const (
@@ -259,37 +586,239 @@ func (s *fileScanner) checkIncludeAndLoad() int {
 		strings.HasPrefix(string(s.parseBuf), Include_Keyword) &&
 		(s.parseBuf[Include_Len] == ' ' || s.parseBuf[Include_Len] == '\t') {
 
-		fileName := string(s.parseBuf[Include_Len+1:])
-		fileName = strings.Trim(strings.Trim(strings.Trim(fileName, " "), "\t"), "\"")
+		arg := string(s.parseBuf[Include_Len+1:])
+		spec, err := parseInclude(arg)
+		if err != nil {
+			s.step = stateError
+			s.err = s.bufPos.errorSyntax(err.Error())
+			return scanError
+		}
+
+		if s.tree != nil {
+			// the syntax tree keeps the include, but does not load it
+			if len(s.frames) == 0 {
+				include := &Node{Kind: IncludeNode, Value: strings.TrimSpace(arg), Line: s.bufPos.line, Column: s.bufPos.column}
+				if s.currentState == parseValue {
+					// an include as value, such as a = include "a.conf"
+					s.tree.field(s.fieldKey(), "", false, s.keyPos).Object = []*Node{include}
+				} else {
+					s.tree.add(include)
+				}
+			}
+			return scanSkipSpace
+		}
 
-		if !filepath.IsAbs(fileName) {
-			fileName = filepath.Join(s.dir, fileName)
+		scans, err := s.loadInclude(spec)
+		if err != nil {
+			s.step = stateError
+			s.err = err
+			return scanError
 		}
 
-		scan := fileScanner{}
-		err := scan.checkValid(fileName)
-		if err == nil {
+		for _, scan := range scans {
 			for _, kv := range scan.kvs {
 				basekeys := []string{}
 				basekeys = append(basekeys, s.baseKeys...)
 				basekeys = append(basekeys, kv.keys...)
-				s.kvs = append(s.kvs, kvPair{basekeys, kv.value})
+				fixSubstitutions(kv.value, joinPath(s.baseKeys))
+				s.kvs = append(s.kvs, kvPair{basekeys, kv.value, kv.pos})
+			}
+			if len(s.frames) == 0 {
+				prefix := joinPath(s.baseKeys)
+				for path, comment := range scan.comments {
+					if len(prefix) > 0 {
+						path = prefix + "." + path
+					}
+					*s.keyComment(path) = *comment
+				}
 			}
-		} else {
-			s.step = stateError
-			s.err = s.errorSyntax("error in load include: " + string(s.parseBuf))
-			return scanError
 		}
 		return scanSkipSpace
 	}
 	return scanContinue
 }
 
-func (s *fileScanner) pushArrayKey() {
+// endObject ends an object at '}'.
+func (s *fileScanner) endObject() int {
+	open := s.close()
+	if frameLen := len(s.frames); frameLen > 0 && !s.frames[frameLen-1].isArray && len(s.keyStack) == 1 {
+		object := &Config{}
+		if err := setKvs(object, s.kvs, false); err != nil {
+			panic(err)
+		}
+		s.popFrame(object)
+		return scanContinue
+	}
+
+	if len(s.baseKeys) > 0 && open.kvs == len(s.kvs) {
+		// an empty object, such as `a {}`, is kept as an object
+		baseKeys := make([]string, len(s.baseKeys))
+		copy(baseKeys, s.baseKeys)
+		s.kvs = append(s.kvs, kvPair{baseKeys, &Config{}, s.keyPos})
+	}
+
+	if s.tree != nil && len(s.frames) == 0 {
+		s.tree.close()
+		s.objectRaw = string(s.data[open.offset:s.bytes])
+	}
+	s.objectKeys = append(s.objectKeys[0:0], s.baseKeys...)
+	s.popKeyStack()
+	s.step = stateEndObject
+	return scanContinue
+}
+
+// stateEndObject is the state after an object value, such as after
+// reading `a { x = 1 }`. An object follows in the same line is merged
+// to it, such as `a { x = 1 } { y = 2 }`.
+func stateEndObject(s *fileScanner, c int) int {
+	switch c {
+	case ' ', '\t':
+		return scanSkipSpace
+	case '{':
+		s.baseKeys = append(s.baseKeys, s.objectKeys[len(s.baseKeys):]...)
+		if s.tree != nil {
+			s.tree.concat = true
+		}
+		s.pushKeyStack()
+		s.step = stateBeginKey
+		return scanContinue
+	case '$':
+		if len(s.frames) == 0 {
+			return s.beginSelfConcat(s.objectKeys, c)
+		}
+	}
+	s.step = stateBeginKey
+	return stateBeginKey(s, c)
+}
+
+// endArray ends an array at ']'.
+func (s *fileScanner) endArray() int {
+	s.close()
+	if frameLen := len(s.frames); frameLen > 0 && s.frames[frameLen-1].isArray {
+		s.popFrame(s.kvs[0].value)
+		return scanContinue
+	}
+
+	if s.tree != nil && len(s.frames) == 0 && s.tree.last != nil {
+		s.tree.last.Value = s.rawValue()
+	}
+	s.step = stateEndArray
+	s.currentState = parseKey
+	return scanContinue
+}
+
+// stateEndArray is the state after an array value, such as after
+// reading `a = [1]`. An array follows in the same line is appended
+// to it, such as `a = [1] [2]`.
+func stateEndArray(s *fileScanner, c int) int {
+	switch c {
+	case ' ', '\t':
+		return scanSkipSpace
+	case '[':
+		s.open('[')
+		s.step = stateBeginValue
+		s.currentState = parseArrayValue
+		return scanContinue
+	case '$':
+		if len(s.frames) == 0 {
+			s.objectRaw = ""
+			return s.beginSelfConcat(s.kvs[len(s.kvs)-1].keys, c)
+		}
+	}
+	s.step = stateBeginKey
+	return stateBeginKey(s, c)
+}
+
+// pushFrame saves the state of the array, to scan an object or
+// array element of it.
+func (s *fileScanner) pushFrame(isArray bool) {
+	s.frames = append(s.frames, scanFrame{isArray, s.appending, false, nil, s.baseKeys, s.keyStack, s.keyPos, s.kvs})
+	s.baseKeys, s.keyStack, s.kvs = nil, nil, nil
+	s.appending = false
+	if isArray {
+		s.open('[')
+		s.kvs = []kvPair{{nil, []interface{}{}, s.keyPos}}
+		s.step = stateBeginValue
+		s.currentState = parseArrayValue
+	} else {
+		s.pushKeyStack()
+		s.step = stateBeginKey
+		s.currentState = parseKey
+	}
+}
+
+// pushConcatFrame is pushFrame for an object or array element, which is
+// concatenated to base, such as [${list} [1]] or [{a = 1} {b = 2}].
+func (s *fileScanner) pushConcatFrame(isArray bool, base interface{}) {
+	s.pushFrame(isArray)
+	frame := &s.frames[len(s.frames)-1]
+	frame.concat, frame.base = true, base
+}
+
+// popFrame restores the state of the array, and appends the element.
+func (s *fileScanner) popFrame(elem interface{}) {
+	frame := s.frames[len(s.frames)-1]
+	s.frames = s.frames[:len(s.frames)-1]
+	s.baseKeys, s.keyStack, s.keyPos, s.kvs = frame.baseKeys, frame.keyStack, frame.keyPos, frame.kvs
+	s.appending = frame.appending
+
+	if frame.concat && frame.isArray {
+		elem = concatenation{frame.base, elem}
+	} else if frame.concat {
+		elem = &merge{frame.base, elem.(*Config)}
+	}
+
+	s.kvs[len(s.kvs)-1].appendElem(elem)
+	if s.appending {
+		// the object or array of +=
+		if s.tree != nil && len(s.frames) == 0 && s.tree.last != nil {
+			s.tree.last.Value = s.rawValue()
+		}
+		s.appending = false
+		s.step = stateEndArray
+		s.currentState = parseKey
+		return
+	}
+	s.step = stateEndArrayElement
+	s.currentState = parseArrayValue
+}
+
+// appendElem appends elem to the array of kv, which may be the last
+// piece of a concatenation, such as ${base} [elem].
+func (kv *kvPair) appendElem(elem interface{}) {
+	if pieces, ok := kv.value.(concatenation); ok {
+		pieces[len(pieces)-1] = append(pieces[len(pieces)-1].([]interface{}), elem)
+		return
+	}
+	kv.value = append(kv.value.([]interface{}), elem)
+}
+
+// popElem removes the last element of the array of kv, and returns it.
+func (kv *kvPair) popElem() interface{} {
+	elems, _ := kv.value.([]interface{})
+	pieces, isConcat := kv.value.(concatenation)
+	if isConcat {
+		elems = pieces[len(pieces)-1].([]interface{})
+	}
+
+	elem := elems[len(elems)-1]
+	if elems = elems[:len(elems)-1]; isConcat {
+		pieces[len(pieces)-1] = elems
+	} else {
+		kv.value = elems
+	}
+	return elem
+}
+
+func (s *fileScanner) pushArrayKey(value interface{}) {
 	baseKeys := make([]string, len(s.baseKeys))
 	copy(baseKeys, s.baseKeys)
+	s.lastKey = joinPath(baseKeys)
+	if s.tree != nil && len(s.frames) == 0 {
+		s.tree.field(s.fieldKey(), "", s.appending, s.keyPos)
+	}
 
-	s.kvs = append(s.kvs, kvPair{baseKeys, []interface{}{}})
+	s.kvs = append(s.kvs, kvPair{baseKeys, value, s.keyPos})
 
 	if stackLen := len(s.keyStack); stackLen > 0 {
 		stack := s.keyStack[stackLen-1]
@@ -300,11 +829,12 @@ func (s *fileScanner) pushArrayKey() {
 }
 
 func (s *fileScanner) pushArrayValue() {
-	kv := &s.kvs[len(s.kvs)-1]
-	kv.value = append(kv.value.([]interface{}), s.parseBufValue())
+	s.kvs[len(s.kvs)-1].appendElem(s.parseBufValue())
 
 	s.parseBuf = s.parseBuf[0:0]
 	s.bufType = bufTypeNull
+	s.bufInQuote = false
+	s.bufInSubst = false
 }
 
 // parse buf to type value
@@ -316,11 +846,16 @@ func (s *fileScanner) parseBufValue() (value interface{}) {
 	}
 
 	switch s.bufType {
-	case bufTypeString, bufTypeNoQuoteString:
+	case bufTypeString:
 		if b, ok := stringBytes(s.parseBuf); ok {
 			value = string(b)
 		}
 		// value = string(s.parseBuf)
+	case bufTypeNoQuoteString:
+		if value, err = concatValue(s.parseBuf); err != nil {
+			panic(s.errorSyntax(err.Error()))
+		}
+		setSubstitutionPos(value, s.bufPos)
 	case bufTypeNumber:
 		if value, err = strconv.ParseFloat(string(s.parseBuf), 64); err != nil {
 			panic(s.errorSyntax("number " + string(s.parseBuf) + " parse error: " + err.Error()))
@@ -337,6 +872,21 @@ func (s *fileScanner) trimParseBuf() {
 	s.parseBuf = []byte(strings.TrimRight(string(s.parseBuf), " "))
 }
 
+// hasSpace returns true if the unquoted key buf has spaces or tabs which
+// are not in quotes or at its end.
+func hasSpace(buf []byte) bool {
+	inQuote := false
+	for _, c := range bytes.TrimRight(buf, " \t") {
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+		case !inQuote && (c == ' ' || c == '\t'):
+			return true
+		}
+	}
+	return false
+}
+
 func isSpace(c rune) bool {
 	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
 }
@@ -353,14 +903,13 @@ func stateBeginKey(s *fileScanner, c int) int {
 	case '"':
 		s.step = stateInString
 		s.bufType = bufTypeString
+		s.bufPos = s.position()
 		return scanContinue
 	case '#':
-		s.step = stateComment
+		s.beginComment(true)
 		return scanContinue
 	case '}':
-		s.popKeyStack()
-		s.step = stateBeginKey
-		return scanContinue
+		return s.endObject()
 	}
 
 	if unicode.IsLetter(rune(c)) {
@@ -390,6 +939,30 @@ func stateBeginValue(s *fileScanner, c int) int {
 		}
 	}
 
+	if s.currentState == parseValue && len(s.frames) == 0 {
+		s.valueStart = s.bytes - 1
+	}
+
+	if s.currentState == parseArrayValue {
+		switch c {
+		case '{':
+			s.pushFrame(false)
+			return scanContinue
+		case '[':
+			s.pushFrame(true)
+			return scanContinue
+		case ']':
+			return s.endArray()
+		}
+	}
+
+	if s.appending && (c == '{' || c == '[') {
+		// the object or array is scanned as the element of `${?key} [...]`
+		s.pushArrayKey(concatenation{s.appendBase(), []interface{}{}})
+		s.pushFrame(c == '[')
+		return scanContinue
+	}
+
 	switch c {
 	case '{':
 		s.pushKeyStack()
@@ -397,14 +970,22 @@ func stateBeginValue(s *fileScanner, c int) int {
 		s.currentState = parseKey
 		return scanContinue
 	case '[':
-		s.pushArrayKey()
+		s.open('[')
+		s.pushArrayKey([]interface{}{})
 		s.step = stateBeginValue
 		s.currentState = parseArrayValue
 		return scanContinue
 	case '"':
+		// values keep their quotes in buf, so that a quoted string
+		// can be concatenated with unquoted strings and substitutions
 		s.step = stateInString
-		s.bufType = bufTypeString
-		return scanContinue
+		s.bufType = bufTypeNoQuoteString
+		s.bufInQuote = true
+		return scanAppendBuf
+	case '$':
+		s.step = stateInString
+		s.bufType = bufTypeNoQuoteString
+		return stateInString(s, c)
 	case '-':
 		s.step = stateNeg
 		s.bufType = bufTypeNumber
@@ -414,6 +995,10 @@ func stateBeginValue(s *fileScanner, c int) int {
 		s.bufType = bufTypeNumber
 		return scanAppendBuf
 	case '#':
+		if s.currentState == parseArrayValue {
+			s.beginComment(false)
+			return scanContinue
+		}
 		s.step = stateComment
 		return stateEndValue(s, c)
 	case '\r', '\n':
@@ -440,8 +1025,15 @@ func stateEndValue(s *fileScanner, c int) int {
 		s.step = stateEndValue
 		return scanSkipSpace
 	}
+	if s.bufType == bufTypeNumber && s.currentState != parseKey && (unicode.IsLetter(rune(c)) || c == '$') {
+		// a number and a unit with space between them, such as `10 MB`,
+		// or a number concatenated to a substitution, such as `80 ${b}`
+		s.parseBuf = append(s.parseBuf, ' ')
+		return stateEndNumber(s, c)
+	}
 	switch s.currentState {
 	case parseKey:
+		spaced := s.bufType == bufTypeNoQuoteString && hasSpace(s.parseBuf)
 		s.pushKey()
 		switch c {
 		case ':', '=':
@@ -456,11 +1048,26 @@ func stateEndValue(s *fileScanner, c int) int {
 			s.step = stateBeginKey
 			return scanContinue
 		case '\r', '\n', '#':
-			s.currentState = parseValue
-			s.step = stateEndValue
-			return stateEndValue(s, c)
+			// a key without value is null, but a key with spaces is a
+			// typo, such as `port 80`
+			if s.included || !spaced {
+				s.currentState = parseValue
+				s.step = stateEndValue
+				return stateEndValue(s, c)
+			}
+		case '}', ',':
+			if s.included {
+				s.currentState = parseValue
+				s.step = stateEndValue
+				return stateEndValue(s, c)
+			}
+		case '+':
+			s.step = statePlusEqual
+			return scanContinue
 		}
-		return s.error(c, "after object key")
+		s.step = stateError
+		s.err = s.errorSyntax("expected ':' or '=' after key " + strconv.Quote(joinPath(s.baseKeys)))
+		return scanError
 	case parseValue:
 		s.pushValue()
 		s.currentState = parseKey
@@ -469,11 +1076,9 @@ func stateEndValue(s *fileScanner, c int) int {
 			s.step = stateBeginKey
 			return scanContinue
 		case '}':
-			s.popKeyStack()
-			s.step = stateBeginKey
-			return scanContinue
+			return s.endObject()
 		case '#':
-			s.step = stateComment
+			s.beginComment(false)
 			return scanContinue
 		}
 		return s.error(c, "after object key:value pair")
@@ -484,11 +1089,9 @@ func stateEndValue(s *fileScanner, c int) int {
 			s.step = stateBeginValue
 			return scanContinue
 		case ']':
-			s.step = stateBeginKey
-			s.currentState = parseKey
-			return scanContinue
+			return s.endArray()
 		case '#':
-			s.step = stateComment
+			s.beginComment(false)
 			return scanContinue
 		}
 		return s.error(c, "after array element")
@@ -496,6 +1099,40 @@ func stateEndValue(s *fileScanner, c int) int {
 	return s.error(c, "stateEndValue, error state")
 }
 
+// statePlusEqual is the state after reading `key +`.
+func statePlusEqual(s *fileScanner, c int) int {
+	if c != '=' {
+		return s.error(c, "after '+', expected '='")
+	}
+	s.appending = true
+	s.currentState = parseValue
+	s.step = stateBeginValue
+	return scanContinue
+}
+
+// stateEndArrayElement is the state after completing an object or
+// array element of array, such as after reading `[{}` or `[[1]`.
+func stateEndArrayElement(s *fileScanner, c int) int {
+	switch c {
+	case ' ', '\t':
+		return scanSkipSpace
+	case ',', '\r', '\n':
+		s.step = stateBeginValue
+		return scanContinue
+	case ']':
+		return s.endArray()
+	case '#':
+		s.beginComment(false)
+		return scanContinue
+	case '{', '[':
+		// an object or array concatenated to the element, such as
+		// [[1] [2]] or [{a = 1} {b = 2}]
+		s.pushConcatFrame(c == '[', s.kvs[len(s.kvs)-1].popElem())
+		return scanContinue
+	}
+	return s.error(c, "after array element")
+}
+
 // stateInString is the state after reading `"`.
 func stateInString(s *fileScanner, c int) int {
 	// check string end
@@ -514,20 +1151,58 @@ func stateInString(s *fileScanner, c int) int {
 			case '=', '{', '\r', '\n', '#':
 				s.trimParseBuf()
 				return stateEndValue(s, c)
+			case '}', ',', '+':
+				// end of an include, such as `{ include "a.conf" }`, or +=
+				if !s.bufInQuote {
+					s.trimParseBuf()
+					return stateEndValue(s, c)
+				}
+			}
+		}
+		if s.currentState == parseValue || s.currentState == parseArrayValue {
+			switch {
+			case c == '"' && !s.bufInSubst:
+				s.bufInQuote = !s.bufInQuote
+				return scanAppendBuf
+			case s.bufInQuote:
+				// in quote, only check special char
+			case c == '{' && len(s.parseBuf) > 0 && s.parseBuf[len(s.parseBuf)-1] == '$':
+				s.bufInSubst = true
+				return scanAppendBuf
+			case c == '}' && s.bufInSubst:
+				s.bufInSubst = false
+				return scanAppendBuf
+			case s.bufInSubst:
+				if c == '\r' || c == '\n' {
+					return s.error(c, "in substitution")
+				}
+				return scanAppendBuf
 			}
 		}
-		if s.currentState == parseValue {
+		if s.currentState == parseValue && !s.bufInQuote {
 			switch c {
 			case ',', '\r', '\n', '}', '#':
 				s.trimParseBuf()
 				return stateEndValue(s, c)
+			case '{', '[':
+				return s.beginConcat(c)
 			}
 		}
-		if s.currentState == parseArrayValue {
+		if s.currentState == parseArrayValue && !s.bufInQuote {
 			switch c {
 			case ',', '\r', '\n', ']', '#':
 				s.trimParseBuf()
 				return stateEndValue(s, c)
+			case '{', '[':
+				// an object or array concatenated to the element,
+				// such as [${list} [1]]
+				s.trimParseBuf()
+				value := s.parseBufValue()
+				s.parseBuf = s.parseBuf[0:0]
+				s.bufType = bufTypeNull
+				s.bufInSubst = false
+				s.pushConcatFrame(c == '[', value)
+				return scanContinue
 			}
 		}
 	} else {
@@ -550,8 +1225,52 @@ func stateInString(s *fileScanner, c int) int {
 	return scanAppendBuf
 }
 
+// beginComment begins to scan a comment, leading is true when the
+// comment is in its own line before a key.
+func (s *fileScanner) beginComment(leading bool) {
+	s.step = stateComment
+	s.leading = leading
+	s.comment = s.comment[0:0]
+	s.commentPos = s.position()
+}
+
+// endComment saves the scanned comment.
+func (s *fileScanner) endComment() {
+	text := strings.TrimSpace(string(s.comment))
+	if s.tree != nil && len(s.frames) == 0 && s.currentState != parseArrayValue {
+		s.tree.add(&Node{Kind: CommentNode, Value: text, Line: s.commentPos.line, Column: s.commentPos.column})
+	}
+	switch {
+	case s.leading:
+		s.commentLines = append(s.commentLines, text)
+	case len(s.lastKey) > 0 && len(s.frames) == 0:
+		comment := s.keyComment(s.lastKey)
+		if len(comment.trailing) > 0 {
+			text = comment.trailing + " " + text
+		}
+		comment.trailing = text
+	}
+}
+
+// keyComment returns the comment of key path, creates it if not exist.
+func (s *fileScanner) keyComment(path string) *keyComment {
+	if s.comments == nil {
+		s.comments = map[string]*keyComment{}
+	}
+	comment, ok := s.comments[path]
+	if !ok {
+		comment = &keyComment{}
+		s.comments[path] = comment
+	}
+	return comment
+}
+
 func stateComment(s *fileScanner, c int) int {
+	if c != '\n' && c != '\r' {
+		s.comment = append(s.comment, byte(c))
+	}
 	if c == '\n' || c == '\r' {
+		s.endComment()
 		if s.currentState == parseArrayValue {
 			s.step = stateBeginValue
 		} else {
@@ -649,6 +1368,17 @@ func state0(s *fileScanner, c int) int {
 		s.step = stateE
 		return scanAppendBuf
 	}
+	return stateEndNumber(s, c)
+}
+
+// stateEndNumber is the state after a number. A letter after number
+// makes it an unquoted string, such as `30s` or `10 MB`.
+func stateEndNumber(s *fileScanner, c int) int {
+	if s.currentState != parseKey && (unicode.IsLetter(rune(c)) || c == '$') {
+		s.step = stateInString
+		s.bufType = bufTypeNoQuoteString
+		return stateInString(s, c)
+	}
 	return stateEndValue(s, c)
 }
 
@@ -673,7 +1403,7 @@ func stateDot0(s *fileScanner, c int) int {
 		s.step = stateE
 		return scanAppendBuf
 	}
-	return stateEndValue(s, c)
+	return stateEndNumber(s, c)
 }
 
 // stateE is the state after reading the mantissa and e in a number,
@@ -708,7 +1438,7 @@ func stateE0(s *fileScanner, c int) int {
 		s.step = stateE0
 		return scanAppendBuf
 	}
-	return stateEndValue(s, c)
+	return stateEndNumber(s, c)
 }
 
 // stateError is the state after reaching a syntax error,
@@ -720,12 +1450,18 @@ func stateError(s *fileScanner, c int) int {
 // error records an error and switches to the error state.
 func (s *fileScanner) error(c int, context string) int {
 	s.step = stateError
-	s.err = &SyntaxError{"invalid character " + quoteChar(c) + " " + context, s.parseBuf, s.bytes}
+	s.err = s.errorSyntax("invalid character " + quoteChar(c) + " " + context)
 	return scanError
 }
 
 func (s *fileScanner) errorSyntax(context string) *SyntaxError {
-	return &SyntaxError{context, s.parseBuf, s.bytes}
+	pos := s.position()
+	return &SyntaxError{context, s.bytes, pos.file, pos.line, pos.column}
+}
+
+// position returns the position of the current byte.
+func (s *fileScanner) position() position {
+	return position{s.name, s.line, s.column}
 }
 
 // quoteChar formats c as a quoted character literal
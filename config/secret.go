@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// secretPrefix marks a string value as secret, such as
+// password = "secret:hunter2", and a substitution as the name of a secret
+// of Secrets, such as ${secret:db/password}.
+const secretPrefix = "secret:"
+
+// A Secret is a secret value of a config, such as a password. It is
+// written as *** by fmt, JSON and the log package, so a config can be
+// printed and logged. Render writes it in the secret: form in HOCON, YAML
+// and properties, so the output can be edited and read again. Only Value
+// returns the value.
+type Secret struct {
+	value *string // a pointer, so the value is not printed as a field of a struct
+}
+
+// NewSecret returns the secret of value.
+func NewSecret(value string) Secret {
+	return Secret{&value}
+}
+
+// Value returns the value of the secret.
+func (s Secret) Value() string {
+	if s.value == nil {
+		return ""
+	}
+	return *s.value
+}
+
+func (s Secret) String() string {
+	return "***"
+}
+
+func (s Secret) GoString() string {
+	return "config.Secret(***)"
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"***"`), nil
+}
+
+// secretForm returns value in the secret: form if it is a Secret, such as
+// "secret:hunter2".
+func secretForm(value interface{}) (string, bool) {
+	if secret, ok := value.(Secret); ok {
+		return secretPrefix + secret.Value(), true
+	}
+	return "", false
+}
+
+// A SecretResolver returns the secrets of ${secret:name} substitutions,
+// such as from a vault or the files of a secret store, see Loader. found is false if
+// there is no secret of name.
+type SecretResolver interface {
+	ResolveSecret(name string) (secret string, found bool, err error)
+}
+
+// The SecretResolverFunc type is an adapter to use a function as a
+// SecretResolver.
+type SecretResolverFunc func(name string) (string, bool, error)
+
+func (f SecretResolverFunc) ResolveSecret(name string) (string, bool, error) {
+	return f(name)
+}
+
+// lookupSecret returns the secret of the substitution ${secret:name}.
+func (r *resolver) lookupSecret(subst *substitution) (interface{}, bool, error) {
+	name := strings.TrimPrefix(subst.path, secretPrefix)
+	if r.secrets == nil {
+		return nil, false, subst.pos.errorSyntax("could not resolve substitution " + subst.String() + ", Loader.Secrets is not set")
+	}
+
+	secret, found, err := r.secrets.ResolveSecret(name)
+	switch {
+	case err != nil:
+		return nil, false, subst.pos.errorSyntax("could not resolve substitution " + subst.String() + ": " + err.Error())
+	case found:
+		return NewSecret(secret), true, nil
+	case subst.optional:
+		return nil, false, nil
+	}
+	return nil, false, subst.pos.errorSyntax("could not resolve substitution " + subst.String() + " to a secret")
+}
+
+// markSecrets replaces the strings with the secret prefix in value by
+// secrets. The strings of a concatenation are pieces of a value, they
+// are not marked.
+func markSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, secretPrefix) {
+			return NewSecret(v[len(secretPrefix):])
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = markSecrets(elem)
+		}
+	case concatenation:
+		for i, piece := range v {
+			if _, ok := piece.(string); !ok {
+				v[i] = markSecrets(piece)
+			}
+		}
+	case *merge:
+		v.base = markSecrets(v.base)
+		markSecrets(v.object)
+	default:
+		if object := subConfig(value); object != nil {
+			for _, key := range object.Keys() {
+				elem, _ := object.get(key)
+				object.set(key, markSecrets(elem))
+			}
+		}
+	}
+	return value
+}
+
+// Secret returns the secret of key, marked by the secret: prefix or
+// resolved by Secrets. A string value is returned as a secret too, so it
+// is not required to mark a value secret.
+func (c *Config) Secret(key string) (result Secret, found bool) {
+	switch v := c.getValue(key).(type) {
+	case Secret:
+		return v, true
+	case string:
+		return NewSecret(v), true
+	}
+	return Secret{}, false
+}
+
+func (c *Config) SecretDefault(key string, defaultValue Secret) Secret {
+	result, found := c.Secret(key)
+	if !found {
+		result = defaultValue
+	}
+	return result
+}
+
+var errSecretType = errors.New("a secret can only be stored in a config.Secret")
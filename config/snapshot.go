@@ -0,0 +1,295 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Snapshot is an immutable config, which is safe for concurrent use by
+// multiple goroutines. It has the getters of Config, and With returns a
+// new snapshot with a changed value, leaving the snapshot unchanged.
+type Snapshot struct {
+	conf *Config
+}
+
+// NewSnapshot returns a snapshot of a deep copy of conf, so later changes
+// of conf do not change the snapshot.
+func NewSnapshot(conf *Config) *Snapshot {
+	if conf == nil {
+		return &Snapshot{&Config{}}
+	}
+	return &Snapshot{copyValue(conf).(*Config)}
+}
+
+func (s *Snapshot) config() *Config {
+	if s == nil {
+		return nil
+	}
+	return s.conf
+}
+
+// Config returns a deep copy of the config of s, which may be changed.
+func (s *Snapshot) Config() *Config {
+	if s == nil {
+		return nil
+	}
+	return copyValue(s.conf).(*Config)
+}
+
+// With returns a new snapshot with value merged to the path key like
+// Config.Merge. Only the objects and arrays on the path of key are copied,
+// the rest is shared by both snapshots.
+func (s *Snapshot) With(key string, value interface{}) (*Snapshot, error) {
+	value = configValue(value)
+	var keys []string
+	if len(key) > 0 {
+		var err error
+		if keys, err = ParsePath(key); err != nil {
+			return nil, err
+		}
+	} else if subConfig(value) == nil {
+		return s, nil
+	}
+
+	conf, err := withKeys(s.config(), keys, value)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{subConfig(conf)}, nil
+}
+
+// withKeys returns a copy of dst with value merged to the path keys.
+func withKeys(dst interface{}, keys []string, value interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		if src, object := subConfig(value), subConfig(dst); src != nil && object != nil {
+			return mergeObject(copyValue(object).(*Config), copyValue(src).(*Config)), nil
+		}
+		return copyValue(value), nil
+	}
+
+	key := keys[0]
+	if elems, ok := dst.([]interface{}); ok {
+		if index, ok := pathIndex(key); ok {
+			if index >= len(elems) {
+				return nil, errors.New("config: index " + key + " out of range of array with length " + strconv.Itoa(len(elems)))
+			}
+			v, err := withKeys(elems[index], keys[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			elems = append([]interface{}{}, elems...)
+			elems[index] = v
+			return elems, nil
+		}
+	}
+
+	object := &Config{}
+	if src := subConfig(dst); src != nil {
+		for _, k := range src.Keys() {
+			v, _ := src.get(k)
+			object.set(k, v)
+			if comment := src.comment(k); comment != nil {
+				object.setComment(k, comment)
+			}
+		}
+	}
+	previous, _ := object.get(key)
+	v, err := withKeys(previous, keys[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	object.set(key, v)
+	return object, nil
+}
+
+// Unmarshal stores the config of s in the value pointed to by v, see
+// Unmarshal.
+func (s *Snapshot) Unmarshal(v interface{}) error {
+	return Unmarshal(s.Config(), v)
+}
+
+func (s *Snapshot) Int(key string) (int, bool) {
+	return s.config().Int(key)
+}
+
+func (s *Snapshot) IntDefault(key string, defaultValue int) int {
+	return s.config().IntDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Float(key string) (float64, bool) {
+	return s.config().Float(key)
+}
+
+func (s *Snapshot) FloatDefault(key string, defaultValue float64) float64 {
+	return s.config().FloatDefault(key, defaultValue)
+}
+
+func (s *Snapshot) String(key string) (string, bool) {
+	return s.config().String(key)
+}
+
+func (s *Snapshot) StringDefault(key, defaultValue string) string {
+	return s.config().StringDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Bool(key string) (bool, bool) {
+	return s.config().Bool(key)
+}
+
+func (s *Snapshot) BoolDefault(key string, defaultValue bool) bool {
+	return s.config().BoolDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Duration(key string) (time.Duration, bool) {
+	return s.config().Duration(key)
+}
+
+func (s *Snapshot) DurationDefault(key string, defaultValue time.Duration) time.Duration {
+	return s.config().DurationDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Bytes(key string) (int64, bool) {
+	return s.config().Bytes(key)
+}
+
+func (s *Snapshot) BytesDefault(key string, defaultValue int64) int64 {
+	return s.config().BytesDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Secret(key string) (Secret, bool) {
+	return s.config().Secret(key)
+}
+
+func (s *Snapshot) SecretDefault(key string, defaultValue Secret) Secret {
+	return s.config().SecretDefault(key, defaultValue)
+}
+
+// Slice is Config.Slice, the objects and arrays stored in v are copies.
+func (s *Snapshot) Slice(key string, v interface{}) error {
+	value, found := s.config().lookup(key)
+	return sliceValue(key, copyValue(value), found, v)
+}
+
+func (s *Snapshot) Strings(key string) ([]string, bool) {
+	return s.config().Strings(key)
+}
+
+func (s *Snapshot) StringsDefault(key string, defaultValue []string) []string {
+	return s.config().StringsDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Bools(key string) ([]bool, bool) {
+	return s.config().Bools(key)
+}
+
+func (s *Snapshot) BoolsDefault(key string, defaultValue []bool) []bool {
+	return s.config().BoolsDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Ints(key string) ([]int, bool) {
+	return s.config().Ints(key)
+}
+
+func (s *Snapshot) IntsDefault(key string, defaultValue []int) []int {
+	return s.config().IntsDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Floats(key string) ([]float64, bool) {
+	return s.config().Floats(key)
+}
+
+func (s *Snapshot) FloatsDefault(key string, defaultValue []float64) []float64 {
+	return s.config().FloatsDefault(key, defaultValue)
+}
+
+func (s *Snapshot) Durations(key string) ([]time.Duration, bool) {
+	return s.config().Durations(key)
+}
+
+func (s *Snapshot) DurationsDefault(key string, defaultValue []time.Duration) []time.Duration {
+	return s.config().DurationsDefault(key, defaultValue)
+}
+
+// SubConfig returns the snapshot of the object of key, nil if key is not
+// an object.
+func (s *Snapshot) SubConfig(key string) *Snapshot {
+	if conf := s.config().SubConfig(key); conf != nil {
+		return &Snapshot{conf}
+	}
+	return nil
+}
+
+// SubConfigs returns the snapshots of the objects of the array of key.
+// found is false if any element is not an object.
+func (s *Snapshot) SubConfigs(key string) (result []*Snapshot, found bool) {
+	confs, found := s.config().SubConfigs(key)
+	result = make([]*Snapshot, len(confs))
+	for i, conf := range confs {
+		result[i] = &Snapshot{conf}
+	}
+	return
+}
+
+func (s *Snapshot) Keys() []string {
+	return s.config().Keys()
+}
+
+func (s *Snapshot) KeyLen() int {
+	return s.config().KeyLen()
+}
+
+// EachKey calls fun with each key in the order of Keys.
+func (s *Snapshot) EachKey(fun func(key string) error) error {
+	return s.config().EachKey(fun)
+}
+
+// EachSubConfig calls fun with each key and its snapshot in the order of
+// Keys, the snapshot is nil if the value of key is not an object.
+func (s *Snapshot) EachSubConfig(fun func(key string, snap *Snapshot) error) error {
+	return s.config().EachSubConfig(func(key string, conf *Config) error {
+		if conf == nil {
+			return fun(key, nil)
+		}
+		return fun(key, &Snapshot{conf})
+	})
+}
+
+// A SnapshotHolder holds the current snapshot of a config, such as the
+// last loaded config of a hot reloaded file. Load is lock free, so readers
+// are not blocked when the snapshot is swapped. The zero value holds nil.
+type SnapshotHolder struct {
+	mu sync.Mutex // serializes Store and Update
+	v  atomic.Value
+}
+
+// Load returns the current snapshot.
+func (h *SnapshotHolder) Load() *Snapshot {
+	s, _ := h.v.Load().(*Snapshot)
+	return s
+}
+
+// Store sets the current snapshot to s.
+func (h *SnapshotHolder) Store(s *Snapshot) {
+	h.mu.Lock()
+	h.v.Store(s)
+	h.mu.Unlock()
+}
+
+// Update sets the current snapshot to the snapshot returned by fun, which
+// is called with the current snapshot, such as for derivations by With.
+// Updates are serialized, so no update is lost. If fun returns an error,
+// the current snapshot is kept.
+func (h *SnapshotHolder) Update(fun func(s *Snapshot) (*Snapshot, error)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, err := fun(h.Load())
+	if err != nil {
+		return err
+	}
+	h.v.Store(s)
+	return nil
+}
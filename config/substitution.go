@@ -0,0 +1,567 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// A substitution is a ${path} or ${?path} reference to another
+// part of the configuration tree, resolved after parsing.
+type substitution struct {
+	path     string
+	optional bool
+	prefix   string // base key of the include which the substitution comes from
+	pos      position
+}
+
+func (s *substitution) String() string {
+	if s.optional {
+		return "${?" + s.path + "}"
+	}
+	return "${" + s.path + "}"
+}
+
+// A concatenation is a value made from several pieces, such as
+// `${path}":/extra"`. Pieces are strings or substitutions.
+type concatenation []interface{}
+
+// A merge is an object merged onto the value of a substitution, such as
+// ${base} { x = 1 }, the object replaces the value if it is not an object.
+type merge struct {
+	base   interface{}
+	object *Config
+}
+
+// concatValue parses an unquoted value buf, which may contain quoted
+// strings and substitutions, to a string, a substitution or a concatenation.
+func concatValue(buf []byte) (value interface{}, err error) {
+	var pieces concatenation
+	text := []byte{}
+	hasSubst := false
+
+	appendText := func(b []byte) bool {
+		t, ok := stringBytes(b)
+		if ok {
+			text = append(text, t...)
+		}
+		return ok
+	}
+
+	for i := 0; i < len(buf); {
+		switch {
+		case buf[i] == '"':
+			end := i + 1
+			for ; end < len(buf) && buf[end] != '"'; end++ {
+				if buf[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(buf) {
+				return nil, errors.New("unterminated quoted string in " + strconv.Quote(string(buf)))
+			}
+			if !appendText(buf[i+1 : end]) {
+				return nil, errors.New("invalid character or escape in " + strconv.Quote(string(buf)))
+			}
+			i = end + 1
+		case buf[i] == '$' && i+1 < len(buf) && buf[i+1] == '{':
+			end := i + 2
+			for ; end < len(buf) && buf[end] != '}'; end++ {
+			}
+			if end >= len(buf) {
+				return nil, errors.New("unterminated substitution in " + strconv.Quote(string(buf)))
+			}
+
+			subst := &substitution{path: strings.TrimSpace(string(buf[i+2 : end]))}
+			if strings.HasPrefix(subst.path, "?") {
+				subst.optional = true
+				subst.path = strings.TrimSpace(subst.path[1:])
+			}
+			if len(subst.path) == 0 {
+				return nil, errors.New("empty substitution in " + strconv.Quote(string(buf)))
+			}
+
+			if len(text) > 0 {
+				pieces = append(pieces, string(text))
+				text = []byte{}
+			}
+			pieces = append(pieces, subst)
+			hasSubst = true
+			i = end + 1
+		default:
+			end := i
+			for ; end < len(buf) && buf[end] != '"' && !(buf[end] == '$' && end+1 < len(buf) && buf[end+1] == '{'); end++ {
+				if buf[end] == '\\' {
+					end++
+				}
+			}
+			if end > len(buf) {
+				end = len(buf)
+			}
+			if !appendText(buf[i:end]) {
+				return nil, errors.New("invalid character or escape in " + strconv.Quote(string(buf)))
+			}
+			i = end
+		}
+	}
+
+	if !hasSubst {
+		return string(text), nil
+	}
+	if len(text) > 0 {
+		pieces = append(pieces, string(text))
+	}
+	if len(pieces) == 1 {
+		return pieces[0], nil
+	}
+	return pieces, nil
+}
+
+// setSubstitutionPos sets the position of the substitutions in value.
+func setSubstitutionPos(value interface{}, pos position) {
+	switch v := value.(type) {
+	case *substitution:
+		v.pos = pos
+	case concatenation:
+		for _, piece := range v {
+			setSubstitutionPos(piece, pos)
+		}
+	}
+}
+
+// substitutionPos returns the position of the first substitution in value.
+func substitutionPos(value interface{}) position {
+	switch v := value.(type) {
+	case *substitution:
+		return v.pos
+	case concatenation:
+		for _, piece := range v {
+			if pos := substitutionPos(piece); pos.line > 0 {
+				return pos
+			}
+		}
+	case []interface{}:
+		for _, elem := range v {
+			if pos := substitutionPos(elem); pos.line > 0 {
+				return pos
+			}
+		}
+	}
+	return position{}
+}
+
+func (p position) errorSyntax(msg string) *SyntaxError {
+	return &SyntaxError{msg, 0, p.file, p.line, p.column}
+}
+
+// isResolved reports whether the value contains no substitution.
+func isResolved(value interface{}) bool {
+	switch v := value.(type) {
+	case *substitution, concatenation, *merge:
+		return false
+	case []interface{}:
+		for _, elem := range v {
+			if !isResolved(elem) {
+				return false
+			}
+		}
+	case *Config:
+		for _, key := range v.Keys() {
+			if elem, _ := v.get(key); !isResolved(elem) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fixSubstitutions marks every substitution in value as coming from an
+// include at base key prefix, so it is first looked up relative to it.
+func fixSubstitutions(value interface{}, prefix string) {
+	if len(prefix) == 0 {
+		return
+	}
+
+	switch v := value.(type) {
+	case *substitution:
+		if len(v.prefix) > 0 {
+			v.prefix = prefix + "." + v.prefix
+		} else {
+			v.prefix = prefix
+		}
+	case concatenation:
+		for _, piece := range v {
+			fixSubstitutions(piece, prefix)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			fixSubstitutions(elem, prefix)
+		}
+	case *merge:
+		fixSubstitutions(v.base, prefix)
+		for _, key := range v.object.Keys() {
+			elem, _ := v.object.get(key)
+			fixSubstitutions(elem, prefix)
+		}
+	}
+}
+
+// selfReference replaces the substitutions of value that refer to the
+// path keys itself with the previous value of keys, if any.
+func selfReference(value interface{}, keys []string, previous interface{}, found bool) (interface{}, bool) {
+	switch v := value.(type) {
+	case *substitution:
+		path := v.path
+		if len(v.prefix) > 0 {
+			path = v.prefix + "." + v.path
+		}
+		if substKeys, err := ParsePath(path); err == nil && joinPath(substKeys) == joinPath(keys) {
+			if found {
+				return previous, true
+			}
+			// a required self reference without previous value is left
+			// to the resolver, which reports it as a cycle
+			return value, !v.optional
+		}
+	case concatenation:
+		ret := concatenation{}
+		for _, piece := range v {
+			if p, ok := selfReference(piece, keys, previous, found); ok {
+				ret = append(ret, p)
+			}
+		}
+		return ret, len(ret) > 0
+	}
+	return value, true
+}
+
+// resolveSelfConcat resolves the concatenation value, when its only
+// substitutions were self references, such as `a += 2` after `a { x = 1 }`.
+// The errors are reported at pos, the position of the key.
+func resolveSelfConcat(value interface{}, keys []string, pos position) (interface{}, error) {
+	pieces, ok := value.(concatenation)
+	if !ok {
+		return value, nil
+	}
+	for _, piece := range pieces {
+		if !isResolved(piece) {
+			return value, nil
+		}
+	}
+
+	value, _, err := (&resolver{}).resolveConcatenation(pieces, joinPath(keys))
+	if synErr, ok := err.(*SyntaxError); ok && synErr.Line == 0 {
+		synErr.File, synErr.Line, synErr.Column = pos.file, pos.line, pos.column
+	}
+	return value, err
+}
+
+// A resolver replaces all substitutions in a parsed config with
+// the values they refer to.
+type resolver struct {
+	root      *Config
+	lookupEnv func(key string) (string, bool)
+	secrets   SecretResolver
+	resolving []string // paths being resolved, to detect cycles
+}
+
+// resolve resolves the substitutions of config with the settings of
+// loader, which may be nil.
+func resolve(config *Config, loader *Loader) error {
+	// secrets are marked first, so the substitutions of them are secrets
+	markSecrets(config)
+	r := resolver{root: config, lookupEnv: loader.lookupEnv(), secrets: loader.secrets()}
+	return r.resolveConfig(config, "")
+}
+
+func (r *resolver) resolveConfig(conf *Config, path string) error {
+	for _, key := range conf.Keys() {
+		value, found := conf.get(key)
+		if !found || isResolved(value) {
+			continue
+		}
+
+		keyPath := joinKey(path, key)
+
+		// objects are not fields which can be in a cycle, resolve them directly
+		if sub := subConfig(value); sub != nil {
+			if err := r.resolveConfig(sub, keyPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the value may have been resolved when resolve other keys
+		if value, found := conf.get(key); found && !isResolved(value) {
+			v, found, err := r.resolveField(value, keyPath)
+			if err != nil {
+				return err
+			}
+			if found {
+				conf.set(key, v)
+			} else {
+				conf.remove(key)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *resolver) resolveField(value interface{}, path string) (interface{}, bool, error) {
+	for _, p := range r.resolving {
+		if p == path {
+			return nil, false, substitutionPos(value).errorSyntax("cycle in substitution: " + strings.Join(append(r.resolving, path), " -> "))
+		}
+	}
+
+	r.resolving = append(r.resolving, path)
+	defer func() {
+		r.resolving = r.resolving[:len(r.resolving)-1]
+	}()
+
+	return r.resolveValue(value, path)
+}
+
+func (r *resolver) resolveValue(value interface{}, path string) (interface{}, bool, error) {
+	switch v := value.(type) {
+	case *substitution:
+		return r.lookup(v)
+	case concatenation:
+		return r.resolveConcatenation(v, path)
+	case *merge:
+		return r.resolveMerge(v, path)
+	case []interface{}:
+		ret := make([]interface{}, 0, len(v))
+		for _, elem := range v {
+			e, found, err := r.resolveValue(elem, path)
+			if err != nil {
+				return nil, false, err
+			}
+			if found {
+				ret = append(ret, e)
+			}
+		}
+		return ret, true, nil
+	case *Config:
+		return v, true, r.resolveConfig(v, path)
+	}
+	return value, true, nil
+}
+
+func (r *resolver) resolveConcatenation(pieces concatenation, path string) (interface{}, bool, error) {
+	var values []interface{}
+	for _, piece := range pieces {
+		v, found, err := r.resolveValue(piece, path)
+		if err != nil {
+			return nil, false, err
+		}
+		if found {
+			values = append(values, v)
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+	if len(values) == 1 {
+		if _, ok := pieces[0].(*substitution); ok && len(pieces) == 1 {
+			return values[0], true, nil
+		}
+	}
+
+	// array concatenation, such as ${list} [1, 2]
+	if _, ok := values[0].([]interface{}); ok {
+		ret := []interface{}{}
+		for _, v := range values {
+			if isSpaces(v) {
+				continue
+			}
+			elems, ok := v.([]interface{})
+			if !ok {
+				return nil, false, substitutionPos(pieces).errorSyntax("can not concatenate array with non-array value at " + path)
+			}
+			ret = append(ret, elems...)
+		}
+		return ret, true, nil
+	}
+
+	// object concatenation, such as { x = 1 } ${base}, the later objects
+	// are merged onto the earlier ones
+	if object := subConfig(values[0]); object != nil {
+		ret := copyValue(object).(*Config)
+		for _, v := range values[1:] {
+			if isSpaces(v) {
+				continue
+			}
+			object := subConfig(v)
+			if object == nil {
+				return nil, false, substitutionPos(pieces).errorSyntax("can not concatenate object with non-object value at " + path)
+			}
+			ret = mergeObject(ret, copyValue(object).(*Config))
+		}
+		return ret, true, nil
+	}
+
+	buf := []byte{}
+	secret := false // a string with a secret is a secret
+	for _, v := range values {
+		switch v := v.(type) {
+		case Secret:
+			buf = append(buf, v.Value()...)
+			secret = true
+		case string:
+			buf = append(buf, v...)
+		case float64:
+			buf = strconv.AppendFloat(buf, v, 'f', -1, 64)
+		case int:
+			buf = strconv.AppendInt(buf, int64(v), 10)
+		case bool:
+			buf = strconv.AppendBool(buf, v)
+		case nil:
+			buf = append(buf, "null"...)
+		default:
+			return nil, false, substitutionPos(pieces).errorSyntax("can not concatenate object or array to string at " + path)
+		}
+	}
+	if secret {
+		return NewSecret(string(buf)), true, nil
+	}
+	return string(buf), true, nil
+}
+
+// isSpaces reports whether value is the whitespace between the arrays or
+// objects of a concatenation, such as in [1] ${more}.
+func isSpaces(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+func (r *resolver) resolveMerge(m *merge, path string) (interface{}, bool, error) {
+	base, found, err := r.resolveValue(m.base, path)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = r.resolveConfig(m.object, path); err != nil {
+		return nil, false, err
+	}
+
+	if object := subConfig(base); found && object != nil {
+		return mergeObject(copyValue(object).(*Config), m.object), true, nil
+	}
+	return m.object, true, nil
+}
+
+// mergeObject merges the fields of src to dst, objects in both of them
+// are merged, other values of src replace the values of dst.
+func mergeObject(dst, src *Config) *Config {
+	for _, key := range src.Keys() {
+		value, _ := src.get(key)
+		previous, _ := dst.get(key)
+		if s, d := subConfig(value), subConfig(previous); s != nil && d != nil {
+			value = mergeObject(d, s)
+		}
+		dst.set(key, value)
+		if comment := src.comment(key); comment != nil {
+			dst.setComment(key, comment)
+		}
+	}
+	return dst
+}
+
+// copyValue returns a deep copy of value, keeping the order and the
+// comments of objects.
+func copyValue(value interface{}) interface{} {
+	if object := subConfig(value); object != nil {
+		ret := &Config{}
+		for _, key := range object.Keys() {
+			elem, _ := object.get(key)
+			ret.set(key, copyValue(elem))
+			if comment := object.comment(key); comment != nil {
+				ret.setComment(key, comment)
+			}
+		}
+		return ret
+	}
+	if elems, ok := value.([]interface{}); ok {
+		ret := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			ret[i] = copyValue(elem)
+		}
+		return ret
+	}
+	return value
+}
+
+// lookup returns the resolved value of the path of subst.
+func (r *resolver) lookup(subst *substitution) (interface{}, bool, error) {
+	if strings.HasPrefix(subst.path, secretPrefix) {
+		return r.lookupSecret(subst)
+	}
+
+	if len(subst.prefix) > 0 {
+		if v, found, err := r.lookupPath(subst.prefix + "." + subst.path); err != nil || found {
+			return v, found, err
+		}
+	}
+
+	v, found, err := r.lookupPath(subst.path)
+	if err != nil || found {
+		return v, found, err
+	}
+
+	if r.lookupEnv != nil {
+		if env, ok := r.lookupEnv(subst.path); ok {
+			return env, true, nil
+		}
+	}
+
+	if subst.optional {
+		return nil, false, nil
+	}
+
+	return nil, false, subst.pos.errorSyntax("could not resolve substitution " + subst.String() + " to a value")
+}
+
+func (r *resolver) lookupPath(path string) (interface{}, bool, error) {
+	keys, err := ParsePath(path)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	var value interface{} = r.root
+	for i, key := range keys {
+		// the elements of an array are resolved with the array
+		if elems, ok := value.([]interface{}); ok {
+			index, ok := pathIndex(key)
+			if !ok || index >= len(elems) {
+				return nil, false, nil
+			}
+			value = elems[index]
+			continue
+		}
+
+		ops := subConfig(value)
+		if ops == nil {
+			return nil, false, nil
+		}
+
+		var found bool
+		if value, found = ops.get(key); !found {
+			return nil, false, nil
+		}
+
+		if subConfig(value) == nil && !isResolved(value) {
+			v, found, err := r.resolveField(value, joinPath(keys[:i+1]))
+			if err != nil {
+				return nil, false, err
+			}
+			if !found {
+				ops.remove(key)
+				return nil, false, nil
+			}
+			ops.set(key, v)
+			value = v
+		}
+	}
+	return value, true, nil
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var durationUnits = map[string]time.Duration{}
+
+var byteUnits = map[string]float64{}
+
+func init() {
+	for _, u := range []struct {
+		names []string
+		unit  time.Duration
+	}{
+		{[]string{"ns", "nano", "nanos", "nanosecond", "nanoseconds"}, time.Nanosecond},
+		{[]string{"us", "micro", "micros", "microsecond", "microseconds"}, time.Microsecond},
+		{[]string{"ms", "milli", "millis", "millisecond", "milliseconds"}, time.Millisecond},
+		{[]string{"s", "second", "seconds"}, time.Second},
+		{[]string{"m", "minute", "minutes"}, time.Minute},
+		{[]string{"h", "hour", "hours"}, time.Hour},
+		{[]string{"d", "day", "days"}, 24 * time.Hour},
+	} {
+		for _, name := range u.names {
+			durationUnits[name] = u.unit
+		}
+	}
+
+	for _, name := range []string{"B", "b", "byte", "bytes"} {
+		byteUnits[name] = 1
+	}
+
+	// powers of ten are kB, kilobyte, kilobytes ... and powers of two
+	// are K, k, Ki, KiB, kibibyte, kibibytes ...
+	prefixes := []struct{ si, iec, short, siName, iecName string }{
+		{"kB", "KiB", "K", "kilo", "kibi"},
+		{"MB", "MiB", "M", "mega", "mebi"},
+		{"GB", "GiB", "G", "giga", "gibi"},
+		{"TB", "TiB", "T", "tera", "tebi"},
+		{"PB", "PiB", "P", "peta", "pebi"},
+		{"EB", "EiB", "E", "exa", "exbi"},
+	}
+	for i, p := range prefixes {
+		power := float64(i + 1)
+		for _, name := range []string{p.si, p.siName + "byte", p.siName + "bytes"} {
+			byteUnits[name] = math.Pow(1000, power)
+		}
+		for _, name := range []string{p.iec, p.short, strings.ToLower(p.short), p.iec[:2], p.iecName + "byte", p.iecName + "bytes"} {
+			byteUnits[name] = math.Pow(1024, power)
+		}
+	}
+	// KB is commonly used for kB
+	byteUnits["KB"] = 1000
+}
+
+// splitUnit splits a string such as "10 ms" to number and unit name.
+func splitUnit(s string) (number float64, unit string, err error) {
+	s = strings.TrimSpace(s)
+	i := strings.LastIndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+	unit = s[i+1:]
+
+	if number, err = strconv.ParseFloat(strings.TrimSpace(s[:i+1]), 64); err != nil {
+		return 0, "", errors.New("invalid number in " + strconv.Quote(s))
+	}
+	return
+}
+
+// parseDuration parses a HOCON duration, such as "30s" or "10 minutes".
+// A duration without unit is in milliseconds.
+func parseDuration(s string) (time.Duration, error) {
+	number, unit, err := splitUnit(s)
+	if err != nil {
+		return 0, err
+	}
+
+	d := time.Millisecond
+	if len(unit) > 0 {
+		var ok bool
+		if d, ok = durationUnits[unit]; !ok {
+			return 0, errors.New("invalid duration unit " + strconv.Quote(unit) + " in " + strconv.Quote(s))
+		}
+	}
+	return time.Duration(number * float64(d)), nil
+}
+
+// parseBytes parses a HOCON size in bytes, such as "512K" or "10MB".
+// A size without unit is in bytes.
+func parseBytes(s string) (int64, error) {
+	number, unit, err := splitUnit(s)
+	if err != nil {
+		return 0, err
+	}
+
+	b := 1.0
+	if len(unit) > 0 {
+		var ok bool
+		if b, ok = byteUnits[unit]; !ok {
+			return 0, errors.New("invalid size unit " + strconv.Quote(unit) + " in " + strconv.Quote(s))
+		}
+	}
+
+	size := number * b
+	if size >= math.MaxInt64 || size <= math.MinInt64 {
+		return 0, errors.New("size " + strconv.Quote(s) + " overflows int64")
+	}
+	return int64(size), nil
+}
+
+// toDuration converts a duration string, or a number of milliseconds,
+// to time.Duration.
+func toDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case string:
+		d, err := parseDuration(v)
+		if err != nil {
+			// also accept go duration, such as 1h30m
+			if gd, goErr := time.ParseDuration(v); goErr == nil {
+				return gd, nil
+			}
+		}
+		return d, err
+	case float64:
+		return time.Duration(v * float64(time.Millisecond)), nil
+	case int:
+		return time.Duration(v) * time.Millisecond, nil
+	case time.Duration:
+		return v, nil
+	}
+	return 0, errors.New("not a duration")
+}
+
+// toBytes converts a size string, or a number of bytes, to int64.
+func toBytes(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case string:
+		return parseBytes(v)
+	case float64:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	}
+	return 0, errors.New("not a size in bytes")
+}
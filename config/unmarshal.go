@@ -0,0 +1,291 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/tbud/x/meta"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// An UnmarshalTypeError describes a config value that was
+// not appropriate for a value of a specific Go type.
+type UnmarshalTypeError struct {
+	Key   string       // key of the config value
+	Value interface{}  // config value
+	Type  reflect.Type // type of Go value it could not be assigned to
+	Err   error        // error of the conversion, may be nil
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	msg := "config: cannot unmarshal " + describeValue(e.Value) + " of key " + strconv.Quote(e.Key) + " into Go value of type " + e.Type.String()
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "string " + strconv.Quote(v)
+	case float64, int:
+		return fmt.Sprintf("number %v", v)
+	case bool:
+		return "bool " + strconv.FormatBool(v)
+	case []interface{}:
+		return "array"
+	}
+	if subConfig(value) != nil {
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	secretType          = reflect.TypeOf(Secret{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshal stores the values of conf in the struct pointed to by v.
+//
+// Struct fields are matched by the name of the hocon tag, such as
+// `hocon:"name,omitempty"`, and by the field name with first rune lower
+// cased when there is no tag. A tag name of "-" skips the field.
+// Unmarshal recurses into nested structs, pointers, maps with string keys
+// and slices. time.Duration fields accept duration strings such as "30s",
+// and types implementing encoding.TextUnmarshaler accept strings.
+// Keys which are not in conf leave the field unchanged.
+func Unmarshal(conf *Config, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("config: Unmarshal(" + fmt.Sprintf("%T", v) + "), v must be a non-nil pointer")
+	}
+
+	if conf == nil {
+		return nil
+	}
+	return unmarshalValue("", conf, rv.Elem())
+}
+
+func unmarshalStruct(key string, conf *Config, rv reflect.Value) error {
+	metas, err := meta.HoconMeta(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for i, m := range metas {
+		field := rv.Field(i)
+		sf := rv.Type().Field(i)
+		if m.Skip || !field.CanSet() && !sf.Anonymous {
+			continue
+		}
+
+		// embedded struct without name, fields are in the same config
+		if len(m.Name) == 0 {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					if !field.CanSet() {
+						continue
+					}
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+			if err := unmarshalStruct(key, conf, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, found := conf.lookup(m.Name)
+		if !found && len(m.OriginName) > 0 {
+			value, found = conf.lookup(m.OriginName)
+		}
+		if !found {
+			continue
+		}
+
+		if err := unmarshalValue(joinKey(key, m.Name), value, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalValue(key string, value interface{}, rv reflect.Value) error {
+	typeError := func(err error) error {
+		return &UnmarshalTypeError{key, value, rv.Type(), err}
+	}
+
+	if rv.Kind() == reflect.Ptr {
+		if value == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalValue(key, value, rv.Elem())
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	if rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		s, ok := value.(string)
+		if !ok {
+			return typeError(nil)
+		}
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return typeError(err)
+		}
+		return nil
+	}
+
+	if rv.Type() == secretType {
+		switch v := value.(type) {
+		case Secret:
+			rv.Set(reflect.ValueOf(v))
+		case string:
+			rv.Set(reflect.ValueOf(NewSecret(v)))
+		default:
+			return typeError(nil)
+		}
+		return nil
+	}
+	if _, ok := value.(Secret); ok && rv.Kind() != reflect.Interface {
+		return typeError(errSecretType)
+	}
+
+	if rv.Type() == durationType {
+		d, err := toDuration(value)
+		if err != nil {
+			return typeError(err)
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			rv.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return typeError(err)
+			}
+			rv.SetBool(b)
+		default:
+			return typeError(nil)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, err := toNumber(value)
+		// float64 can not hold every int64, f is checked before the
+		// conversion which is undefined out of the range
+		if err != nil || f != math.Trunc(f) || f < -1<<63 || f >= 1<<63 || rv.OverflowInt(int64(f)) {
+			return typeError(err)
+		}
+		rv.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, err := toNumber(value)
+		if err != nil || f < 0 || f != math.Trunc(f) || f >= 1<<64 || rv.OverflowUint(uint64(f)) {
+			return typeError(err)
+		}
+		rv.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, err := toNumber(value)
+		if err != nil || rv.OverflowFloat(f) {
+			return typeError(err)
+		}
+		rv.SetFloat(f)
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			rv.SetString(v)
+		case float64:
+			rv.SetString(strconv.FormatFloat(v, 'f', -1, 64))
+		case int:
+			rv.SetString(strconv.Itoa(v))
+		case bool:
+			rv.SetString(strconv.FormatBool(v))
+		default:
+			return typeError(nil)
+		}
+	case reflect.Slice, reflect.Array:
+		if object := subConfig(value); object != nil {
+			elems, err := numericArray(object)
+			if err != nil {
+				return typeError(err)
+			}
+			value = elems
+		}
+		values := reflect.ValueOf(value)
+		if values.Kind() != reflect.Slice {
+			return typeError(nil)
+		}
+
+		n := values.Len()
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), n, n))
+		} else if n > rv.Len() {
+			return typeError(errors.New("too many elements"))
+		}
+
+		for i := 0; i < n; i++ {
+			if err := unmarshalValue(joinKey(key, strconv.Itoa(i)), values.Index(i).Interface(), rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		conf := subConfig(value)
+		if conf == nil || rv.Type().Key().Kind() != reflect.String {
+			return typeError(nil)
+		}
+
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for _, k := range conf.Keys() {
+			v, _ := conf.get(k)
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := unmarshalValue(joinKey(key, k), v, elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), elem)
+		}
+	case reflect.Struct:
+		conf := subConfig(value)
+		if conf == nil {
+			return typeError(nil)
+		}
+		return unmarshalStruct(key, conf, rv)
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return typeError(nil)
+		}
+		rv.Set(reflect.ValueOf(value))
+	default:
+		return typeError(nil)
+	}
+	return nil
+}
+
+func toNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, errors.New("not a number")
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Watcher reloads a config file when the file or one of its includes
+// changes. The files are polled by modification time and size.
+type Watcher struct {
+	fileName string
+	onChange func(conf *Config, changed []string)
+	loader   Loader // settings of the reloads
+
+	mu     sync.RWMutex
+	conf   *Config
+	err    error
+	states map[string]fileState
+
+	snapshot SnapshotHolder
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exist   bool
+}
+
+func statFile(fileName string) fileState {
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{fi.ModTime(), fi.Size(), true}
+}
+
+func statFiles(files []string) map[string]fileState {
+	states := map[string]fileState{}
+	for _, file := range files {
+		states[file] = statFile(file)
+	}
+	return states
+}
+
+// Watch loads fileName and watches it and its includes. When the config
+// changes, onChange is called with the new config and the sorted key paths
+// whose values are changed, added or removed. If the changed files can not
+// be loaded, the last good config is kept, and the error is reported by Err.
+func Watch(fileName string, onChange func(conf *Config, changed []string)) (*Watcher, error) {
+	return (&Loader{}).Watch(fileName, onChange)
+}
+
+// Watch is like the function Watch, the files are loaded with the
+// settings of l, which are copied, so later changes of l are not used.
+func (l *Loader) Watch(fileName string, onChange func(conf *Config, changed []string)) (*Watcher, error) {
+	conf, files, err := l.load(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		fileName: fileName,
+		onChange: onChange,
+		loader:   *l,
+		conf:     conf,
+		states:   statFiles(files),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.snapshot.Store(NewSnapshot(conf))
+	go w.run(l.watchInterval())
+	return w, nil
+}
+
+// Config returns the last good config.
+func (w *Watcher) Config() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.conf
+}
+
+// Snapshot returns the snapshot of the last good config, which is
+// swapped atomically when the config is reloaded.
+func (w *Watcher) Snapshot() *Snapshot {
+	return w.snapshot.Load()
+}
+
+// Err returns the error of the last reload, or nil if it succeeded.
+func (w *Watcher) Err() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.err
+}
+
+// Close stops watching. It waits for the running onChange to return.
+func (w *Watcher) Close() error {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) run(interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if w.modified() {
+				w.reload()
+			}
+		}
+	}
+}
+
+func (w *Watcher) modified() bool {
+	for file, state := range w.states {
+		if statFile(file) != state {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) reload() {
+	conf, files, err := w.loader.load(w.fileName)
+
+	w.mu.Lock()
+	// watch the files of a failed load too, so fixing them is seen
+	w.states = statFiles(files)
+	w.err = err
+	old := w.conf
+	if err == nil {
+		w.conf = conf
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		return
+	}
+	w.snapshot.Store(NewSnapshot(conf))
+
+	if changed := changedKeys(old, conf); len(changed) > 0 && w.onChange != nil {
+		w.onChange(conf, changed)
+	}
+}
+
+// changedKeys returns the sorted key paths whose values differ in a and b.
+func changedKeys(a, b *Config) []string {
+	changed := []string{}
+	diffConfig("", a, b, &changed)
+	sort.Strings(changed)
+	return changed
+}
+
+func diffConfig(prefix string, a, b *Config, changed *[]string) {
+	keys := map[string]bool{}
+	for _, key := range a.Keys() {
+		keys[key] = true
+	}
+	for _, key := range b.Keys() {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		path := joinKey(prefix, key)
+		va, foundA := a.get(key)
+		vb, foundB := b.get(key)
+
+		subA, subB := subConfig(va), subConfig(vb)
+		if subA == nil && subB == nil {
+			if foundA != foundB || !reflect.DeepEqual(mapValue(va), mapValue(vb)) {
+				*changed = append(*changed, path)
+			}
+			continue
+		}
+
+		// a value replaced by an object, or an object replaced by a value
+		if subA == nil && foundA || subB == nil && foundB {
+			*changed = append(*changed, path)
+		}
+		diffConfig(path, subA, subB, changed)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"strings"
+	"unicode"
+)
+
+// yamlIndent is the indent of YAML, which does not allow tabs.
+const yamlIndent = "  "
+
+// yamlString returns s unquoted if it is a plain YAML scalar which is read
+// as the string s, else quoted.
+func yamlString(s string) string {
+	plain := len(s) > 0
+	for i, c := range s {
+		if !(unicode.IsLetter(c) || c == '_' || c == '/' || i > 0 && (unicode.IsDigit(c) || c == '-' || c == '.')) {
+			plain = false
+			break
+		}
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+		plain = false
+	}
+
+	if plain {
+		return s
+	}
+	return hoconKey(s)
+}
+
+// yamlCollection returns true if value is an object or an array which is
+// not empty, which is written as a YAML block.
+func yamlCollection(value interface{}) bool {
+	if object := subConfig(value); object != nil {
+		return object.KeyLen() > 0
+	}
+	elems, ok := elements(value)
+	return ok && len(elems) > 0
+}
+
+// yamlValue writes value as YAML, the first line of an object or an array
+// is written at the current position, the next lines are indented by depth.
+func (r *renderer) yamlValue(value interface{}, depth int) {
+	indent := func(depth int) {
+		r.w.WriteByte('\n')
+		r.w.WriteString(strings.Repeat(yamlIndent, depth))
+	}
+
+	if object := subConfig(value); object != nil {
+		if object.KeyLen() == 0 {
+			r.w.WriteString("{}")
+			return
+		}
+
+		for i, key := range object.orderedKeys(r.opts.KeepOrder) {
+			if i > 0 {
+				indent(depth)
+			}
+			r.w.WriteString(yamlString(key))
+			r.w.WriteByte(':')
+
+			value, _ := object.get(key)
+			if yamlCollection(value) {
+				indent(depth + 1)
+				r.yamlValue(value, depth+1)
+			} else {
+				r.w.WriteByte(' ')
+				r.yamlValue(value, depth+1)
+			}
+		}
+		return
+	}
+
+	if elems, ok := elements(value); ok {
+		if len(elems) == 0 {
+			r.w.WriteString("[]")
+			return
+		}
+
+		for i, elem := range elems {
+			if i > 0 {
+				indent(depth)
+			}
+			r.w.WriteString("- ")
+			r.yamlValue(elem, depth+1)
+		}
+		return
+	}
+
+	if s, ok := value.(string); ok {
+		r.w.WriteString(yamlString(s))
+		return
+	}
+	if s, ok := secretForm(value); ok {
+		r.w.WriteString(yamlString(s))
+		return
+	}
+	r.scalar(value)
+}
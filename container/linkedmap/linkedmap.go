@@ -79,3 +79,21 @@ func (l *LinkedMap) Each(fun func(key, value interface{}) error) error {
 	}
 	return nil
 }
+
+func (l *LinkedMap) Len() int {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return len(l.m)
+}
+
+func (l *LinkedMap) Keys() []interface{} {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	keys := make([]interface{}, 0, len(l.m))
+	for e := l.link.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value)
+	}
+	return keys
+}
@@ -31,3 +31,21 @@ func TestLinkedmapGetAndIsExist(t *testing.T) {
 		t.Error("remove ddd must be false")
 	}
 }
+
+func TestLinkedmapKeys(t *testing.T) {
+	m := New()
+
+	m.Append("ttt", "1")
+	m.Append("abc", "2")
+	m.Append("ppp", "3")
+	m.Append("ttt", "4")
+	m.Remove("abc")
+
+	if m.Len() != 2 {
+		t.Errorf("want len 2, got %d", m.Len())
+	}
+
+	if keys := m.Keys(); len(keys) != 2 || keys[0] != "ttt" || keys[1] != "ppp" {
+		t.Errorf("want keys [ttt ppp], got %v", keys)
+	}
+}
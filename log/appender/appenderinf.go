@@ -12,7 +12,29 @@ type Appender interface {
 	NeedTime() bool
 }
 
-type AppenderMaker func(conf config.Config) (Appender, error)
+// A RefAppender is an appender which appends to other appenders referenced
+// by name, such as Async. After all appenders of a logger are created,
+// SetAppendRefs is called with the appenders of the names of AppendRefs.
+type RefAppender interface {
+	Appender
+	AppendRefs() []string
+	SetAppendRefs(appenders []Appender) error
+}
+
+// A Flusher is an appender which buffers messages, Flush returns after the
+// buffered messages are appended.
+type Flusher interface {
+	Flush() error
+}
+
+// A Closer is an appender which holds resources, such as the file and the
+// SIGHUP watcher of File, Close releases them. Appenders are closed by
+// the Close of the logger.
+type Closer interface {
+	Close() error
+}
+
+type AppenderMaker func(conf *config.Config) (Appender, error)
 
 var appenderMakers = make(map[string]AppenderMaker)
 
@@ -29,7 +51,7 @@ func Register(name string, appenderMaker AppenderMaker) {
 	appenderMakers[name] = appenderMaker
 }
 
-func New(conf config.Config) (appender Appender, err error) {
+func New(conf *config.Config) (appender Appender, err error) {
 	name := conf.StringDefault("type", "Console")
 	if appenderMaker, ok := appenderMakers[name]; ok {
 		appender, err = appenderMaker(conf)
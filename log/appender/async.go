@@ -0,0 +1,212 @@
+package appender
+
+import (
+	"errors"
+	"github.com/tbud/x/config"
+	"github.com/tbud/x/log/common"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	overflowBlock          = "block"            // wait for a free slot
+	overflowDropNewest     = "drop-newest"      // drop the message to append
+	overflowDropBelowLevel = "drop-below-level" // drop messages below droplevel, wait for others
+)
+
+// AsyncAppender queues log messages in a bounded ring, and appends them to
+// the referenced appenders in its own goroutine, so a slow appender does
+// not block the goroutines which log. When the ring is full, the overflow
+// policy blocks, drops the new message, or drops the messages below
+// droplevel and blocks for the others.
+//
+//	appender {
+//		async {
+//			type = Async
+//			appendrefs = [file]
+//			buffersize = 1024
+//			overflow = drop-below-level  # or block, drop-newest
+//			droplevel = warn             # keep warn, error and fatal
+//		}
+//	}
+type AsyncAppender struct {
+	sync.Mutex
+	cond      *sync.Cond
+	refs      []string
+	appenders []Appender
+	overflow  string
+	dropLevel int
+
+	ring    []common.LogMsg
+	head    int
+	count   int
+	busy    bool // a message is being appended
+	closed  bool
+	done    chan struct{}
+	dropped uint64
+
+	needFile bool
+	needTime bool
+}
+
+func (a *AsyncAppender) Append(m *common.LogMsg) error {
+	a.Lock()
+	defer a.Unlock()
+
+	for !a.closed && a.count == len(a.ring) {
+		if a.overflow == overflowDropNewest || a.overflow == overflowDropBelowLevel && m.Level > a.dropLevel {
+			atomic.AddUint64(&a.dropped, 1)
+			return nil
+		}
+		a.cond.Wait()
+	}
+	if a.closed {
+		return errors.New("log: async appender is closed")
+	}
+
+	a.ring[(a.head+a.count)%len(a.ring)] = *m
+	a.count++
+	a.cond.Broadcast()
+	return nil
+}
+
+func (a *AsyncAppender) NeedFile() bool {
+	return a.needFile
+}
+
+func (a *AsyncAppender) NeedTime() bool {
+	return a.needTime
+}
+
+func (a *AsyncAppender) AppendRefs() []string {
+	return a.refs
+}
+
+func (a *AsyncAppender) SetAppendRefs(appenders []Appender) error {
+	a.appenders = appenders
+	for _, appender := range appenders {
+		if appender.NeedFile() {
+			a.needFile = true
+		}
+		if appender.NeedTime() {
+			a.needTime = true
+		}
+	}
+
+	a.done = make(chan struct{})
+	go a.run()
+	return nil
+}
+
+// Dropped returns the number of dropped messages.
+func (a *AsyncAppender) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Len returns the number of queued messages.
+func (a *AsyncAppender) Len() int {
+	a.Lock()
+	defer a.Unlock()
+	return a.count
+}
+
+// Flush waits until the queued messages are appended, and flushes the
+// referenced appenders.
+func (a *AsyncAppender) Flush() error {
+	a.Lock()
+	for a.done != nil && (a.count > 0 || a.busy) {
+		a.cond.Wait()
+	}
+	a.Unlock()
+
+	var err error
+	for _, appender := range a.appenders {
+		if f, ok := appender.(Flusher); ok {
+			if err1 := f.Flush(); err == nil {
+				err = err1
+			}
+		}
+	}
+	return err
+}
+
+// Close appends the queued messages and stops the appender, messages
+// appended later are rejected. The referenced appenders are not closed.
+func (a *AsyncAppender) Close() error {
+	a.Lock()
+	if a.closed {
+		a.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.cond.Broadcast()
+	a.Unlock()
+
+	if a.done != nil {
+		<-a.done
+	}
+	return nil
+}
+
+func (a *AsyncAppender) run() {
+	defer close(a.done)
+
+	for {
+		a.Lock()
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.count == 0 {
+			a.Unlock()
+			return
+		}
+
+		m := a.ring[a.head]
+		a.ring[a.head] = common.LogMsg{}
+		a.head = (a.head + 1) % len(a.ring)
+		a.count--
+		a.busy = true
+		a.cond.Broadcast()
+		a.Unlock()
+
+		for _, appender := range a.appenders {
+			appender.Append(&m)
+		}
+
+		a.Lock()
+		a.busy = false
+		a.cond.Broadcast()
+		a.Unlock()
+	}
+}
+
+func asyncAppender(conf *config.Config) (app Appender, err error) {
+	appender := &AsyncAppender{
+		refs:      conf.StringsDefault("appendrefs", nil),
+		overflow:  strings.ToLower(conf.StringDefault("overflow", overflowBlock)),
+		dropLevel: common.LogStringToLevel(conf.StringDefault("droplevel", "warn")),
+	}
+	appender.cond = sync.NewCond(appender)
+
+	if len(appender.refs) == 0 {
+		return nil, errors.New("async appender appendrefs is empty")
+	}
+	switch appender.overflow {
+	case overflowBlock, overflowDropNewest, overflowDropBelowLevel:
+	default:
+		return nil, errors.New("async appender overflow " + appender.overflow + " not support, must be block, drop-newest or drop-below-level")
+	}
+
+	size := conf.IntDefault("buffersize", 1024)
+	if size <= 0 {
+		return nil, errors.New("async appender buffersize must be positive")
+	}
+	appender.ring = make([]common.LogMsg, size)
+
+	return appender, nil
+}
+
+func init() {
+	Register("Async", asyncAppender)
+}
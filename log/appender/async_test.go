@@ -0,0 +1,128 @@
+package appender
+
+import (
+	"github.com/tbud/x/config"
+	"github.com/tbud/x/log/common"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memAppender records messages, Append waits for gate if it is not nil.
+type memAppender struct {
+	sync.Mutex
+	gate chan struct{}
+	msgs []string
+}
+
+func (m *memAppender) Append(msg *common.LogMsg) error {
+	if m.gate != nil {
+		<-m.gate
+	}
+	m.Lock()
+	m.msgs = append(m.msgs, msg.Msg)
+	m.Unlock()
+	return nil
+}
+
+func (m *memAppender) NeedFile() bool { return false }
+
+func (m *memAppender) NeedTime() bool { return true }
+
+func newAsyncAppender(t *testing.T, conf string, target Appender) *AsyncAppender {
+	c, err := config.Read(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	async := app.(*AsyncAppender)
+	if err = async.SetAppendRefs([]Appender{target}); err != nil {
+		t.Fatal(err)
+	}
+	return async
+}
+
+func TestAsyncFlush(t *testing.T) {
+	mem := &memAppender{}
+	async := newAsyncAppender(t, "type = Async\nappendrefs = [mem]\nbuffersize = 4", mem)
+	if !async.NeedTime() {
+		t.Error("want NeedTime of referenced appender")
+	}
+
+	for _, msg := range []string{"a", "b", "c", "d", "e", "f"} {
+		async.Append(&common.LogMsg{Level: common.LevelInfo, Msg: msg})
+	}
+	async.Flush()
+	if got := strings.Join(mem.msgs, ""); got != "abcdef" {
+		t.Errorf("want all messages in order, get %s", got)
+	}
+
+	async.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "g"})
+	async.Close()
+	if got := strings.Join(mem.msgs, ""); got != "abcdefg" {
+		t.Errorf("want queued message appended by Close, get %s", got)
+	}
+	if err := async.Append(&common.LogMsg{Msg: "h"}); err == nil {
+		t.Error("want error of append after close")
+	}
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	tests := []struct {
+		overflow string
+		want     string
+		dropped  uint64
+	}{
+		{"drop-newest", "123", 3},
+		{"drop-below-level", "123EF", 1},
+	}
+
+	for _, test := range tests {
+		mem := &memAppender{gate: make(chan struct{})}
+		async := newAsyncAppender(t, "type = Async\nappendrefs = [mem]\nbuffersize = 2\noverflow = "+test.overflow, mem)
+
+		// 1 is taken by the blocked appender, 2 and 3 fill the ring
+		async.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "1"})
+		for async.Len() > 0 {
+			runtime.Gosched()
+		}
+		async.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "2"})
+		async.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "3"})
+		async.Append(&common.LogMsg{Level: common.LevelDebug, Msg: "4"})
+		if test.overflow == "drop-newest" {
+			async.Append(&common.LogMsg{Level: common.LevelError, Msg: "E"})
+			async.Append(&common.LogMsg{Level: common.LevelFatal, Msg: "F"})
+			close(mem.gate)
+		} else {
+			// error and fatal wait for free slots
+			go func() {
+				for i := 0; i < 5; i++ {
+					mem.gate <- struct{}{}
+				}
+			}()
+			async.Append(&common.LogMsg{Level: common.LevelError, Msg: "E"})
+			async.Append(&common.LogMsg{Level: common.LevelFatal, Msg: "F"})
+		}
+		async.Close()
+
+		if got := strings.Join(mem.msgs, ""); got != test.want {
+			t.Errorf("overflow %s want messages %s, get %s", test.overflow, test.want, got)
+		}
+		if async.Dropped() != test.dropped {
+			t.Errorf("overflow %s want %d dropped, get %d", test.overflow, test.dropped, async.Dropped())
+		}
+	}
+}
+
+func TestAsyncConfigError(t *testing.T) {
+	for _, conf := range []string{`type = Async`, "type = Async\nappendrefs = [a]\noverflow = drop-oldest", "type = Async\nappendrefs = [a]\nbuffersize = 0"} {
+		c, _ := config.Read(strings.NewReader(conf))
+		if _, err := New(c); err == nil {
+			t.Errorf("want error of config %s", conf)
+		}
+	}
+}
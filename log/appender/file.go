@@ -0,0 +1,386 @@
+package appender
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/tbud/x/config"
+	"github.com/tbud/x/log/common"
+	"github.com/tbud/x/log/layout"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileAppender writes log messages to a file, which is rotated by size
+// and/or by time. A rotated file is renamed to the backup path.period, such
+// as app.log.2006-01-02, optionally compressed by gzip. The file is reopened
+// on SIGHUP, so it can be rotated by external tools such as logrotate.
+//
+//	appender {
+//		file {
+//			type = File
+//			path = logs/app.log
+//			maxsize = 100MB    # rotate when the file exceeds maxsize
+//			rotate = daily     # or hourly, rotate when the period changes
+//			maxbackups = 7     # remove the oldest backups, 0 keeps all
+//			compress = true    # gzip the backups
+//			layout.pattern = "%d{yyyy-MM-dd HH:mm:ss} [%L] %m"
+//		}
+//	}
+type FileAppender struct {
+	sync.Mutex
+	path       string
+	maxSize    int64
+	rotate     string // "daily", "hourly" or empty
+	maxBackups int
+	compress   bool
+
+	file     *os.File
+	size     int64
+	period   time.Time // start of the period of the file
+	layout   layout.Layout
+	buf      []byte
+	needFile bool
+	needTime bool
+	now      func() time.Time
+
+	hup       chan os.Signal
+	closed    chan struct{}
+	compressW sync.WaitGroup
+}
+
+func (f *FileAppender) Append(m *common.LogMsg) (err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.file == nil {
+		if f.isClosed() {
+			return errors.New("log: file appender " + f.path + " is closed")
+		}
+		// the file was not reopened after a failed rotation
+		if err = f.openFile(); err != nil {
+			return err
+		}
+	}
+
+	f.buf = f.buf[:0]
+	if err = f.layout.Format(&f.buf, m); err != nil {
+		return err
+	}
+
+	if f.needRotate(int64(len(f.buf))) {
+		if err = f.rotateFile(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(f.buf)
+	f.size += int64(n)
+	return err
+}
+
+func (f *FileAppender) NeedFile() bool {
+	return f.needFile
+}
+
+func (f *FileAppender) NeedTime() bool {
+	return f.needTime
+}
+
+// Reopen closes and opens the file again, such as after it is moved by an
+// external tool. It is called on SIGHUP.
+func (f *FileAppender) Reopen() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.isClosed() {
+		return nil
+	}
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.openFile()
+}
+
+// Close closes the file, stops watching SIGHUP, and waits for the
+// compression of backups.
+func (f *FileAppender) Close() (err error) {
+	f.Lock()
+	if !f.isClosed() {
+		if f.file != nil {
+			err = f.file.Close()
+			f.file = nil
+		}
+		signal.Stop(f.hup)
+		close(f.closed)
+	}
+	f.Unlock()
+
+	f.compressW.Wait()
+	return
+}
+
+func (f *FileAppender) isClosed() bool {
+	select {
+	case <-f.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *FileAppender) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = fi.Size()
+	// an existing file belongs to the period it was last written in
+	f.period = f.periodStart(f.now())
+	if f.size > 0 {
+		f.period = f.periodStart(fi.ModTime())
+	}
+	return nil
+}
+
+// periodStart returns the start of the rotation period of t.
+func (f *FileAppender) periodStart(t time.Time) time.Time {
+	switch f.rotate {
+	case "daily":
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	case "hourly":
+		y, m, d := t.Date()
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+func (f *FileAppender) needRotate(n int64) bool {
+	if f.maxSize > 0 && f.size > 0 && f.size+n > f.maxSize {
+		return true
+	}
+	return len(f.rotate) > 0 && !f.periodStart(f.now()).Equal(f.period)
+}
+
+// backupLayouts are the time layouts of the backup suffixes, by rotate.
+var backupLayouts = map[string]string{
+	"daily":  "2006-01-02",
+	"hourly": "2006-01-02-15",
+	"":       "2006-01-02T15-04-05",
+}
+
+// backupName returns an unused name of the backup of the file.
+func (f *FileAppender) backupName() string {
+	var suffix string
+	if len(f.rotate) > 0 {
+		suffix = f.period.Format(backupLayouts[f.rotate])
+	} else {
+		suffix = f.now().Format(backupLayouts[""])
+	}
+
+	name := f.path + "." + suffix
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = f.path + "." + suffix + "." + strconv.Itoa(i)
+	}
+	return name
+}
+
+// isBackup returns true if name is the name of a backup of the file, the
+// path with a time suffix, an optional .N and an optional .gz.
+func (f *FileAppender) isBackup(name string) bool {
+	if !strings.HasPrefix(name, f.path+".") {
+		return false
+	}
+	suffix := strings.TrimSuffix(name[len(f.path)+1:], ".gz")
+	if i := strings.LastIndexByte(suffix, '.'); i >= 0 {
+		if _, err := strconv.ParseUint(suffix[i+1:], 10, 0); err != nil {
+			return false
+		}
+		suffix = suffix[:i]
+	}
+
+	for _, layout := range backupLayouts {
+		if _, err := time.Parse(layout, suffix); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// rotateFile renames the file to a backup and opens a new file. If it
+// fails, the file is left closed and is opened again by the next Append.
+func (f *FileAppender) rotateFile() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return err
+	}
+
+	backup := f.backupName()
+	if err := os.Rename(f.path, backup); err != nil {
+		return err
+	}
+	if err := f.openFile(); err != nil {
+		return err
+	}
+
+	if f.compress {
+		f.compressW.Add(1)
+		go func() {
+			defer f.compressW.Done()
+			if compressFile(backup) == nil {
+				f.removeBackups()
+			}
+		}()
+	} else {
+		f.removeBackups()
+	}
+	return nil
+}
+
+func compressFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err1 := gz.Close(); err == nil {
+		err = err1
+	}
+	if err1 := out.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	return os.Remove(name)
+}
+
+// removeBackups removes the oldest backups to keep maxBackups of them.
+func (f *FileAppender) removeBackups() {
+	if f.maxBackups <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		name    string
+		modTime time.Time
+	}
+	backups := []backup{}
+	for _, name := range matches {
+		if !f.isBackup(name) {
+			continue
+		}
+		// a backup being compressed is counted by its .gz
+		if f.compress && !strings.HasSuffix(name, ".gz") && exists(name+".gz") {
+			continue
+		}
+		if fi, err := os.Stat(name); err == nil && !fi.IsDir() {
+			backups = append(backups, backup{name, fi.ModTime()})
+		}
+	}
+	if len(backups) <= f.maxBackups {
+		return
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].name > backups[j].name
+		}
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	for _, b := range backups[f.maxBackups:] {
+		os.Remove(b.name)
+	}
+}
+
+func (f *FileAppender) watchHup() {
+	for {
+		select {
+		case <-f.hup:
+			f.Reopen()
+		case <-f.closed:
+			return
+		}
+	}
+}
+
+func fileAppender(conf *config.Config) (app Appender, err error) {
+	appender := &FileAppender{
+		path:       conf.StringDefault("path", ""),
+		maxSize:    conf.BytesDefault("maxsize", 0),
+		rotate:     strings.ToLower(conf.StringDefault("rotate", "")),
+		maxBackups: conf.IntDefault("maxbackups", 0),
+		compress:   conf.BoolDefault("compress", false),
+		now:        time.Now,
+	}
+
+	if len(appender.path) == 0 {
+		return nil, errors.New("file appender path is empty")
+	}
+	switch appender.rotate {
+	case "", "daily", "hourly":
+	default:
+		return nil, errors.New("file appender rotate " + appender.rotate + " not support, must be daily or hourly")
+	}
+
+	if appender.layout, err = layout.New(conf.SubConfig("layout")); err != nil {
+		return nil, err
+	}
+	appender.needFile = appender.layout.NeedFile()
+	appender.needTime = appender.layout.NeedTime()
+
+	if err = appender.openFile(); err != nil {
+		return nil, err
+	}
+
+	appender.hup = make(chan os.Signal, 1)
+	appender.closed = make(chan struct{})
+	signal.Notify(appender.hup, syscall.SIGHUP)
+	go appender.watchHup()
+
+	return appender, nil
+}
+
+func init() {
+	Register("File", fileAppender)
+}
@@ -0,0 +1,187 @@
+package appender
+
+import (
+	"compress/gzip"
+	"github.com/tbud/x/config"
+	"github.com/tbud/x/log/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newFileAppender(t *testing.T, conf string) *FileAppender {
+	c, err := config.Read(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app.(*FileAppender)
+}
+
+func backups(t *testing.T, path string) []string {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(matches)
+	for i := range matches {
+		matches[i] = filepath.Base(matches[i])
+	}
+	return matches
+}
+
+func TestFileRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	f := newFileAppender(t, `type = File, path = "`+path+`", maxsize = 10, maxbackups = 2`)
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "message"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// each message is "[I]message\n", 11 bytes, so every message is a file
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[I]message\n" {
+		t.Errorf("want one message in file, get %q", data)
+	}
+	if names := backups(t, path); len(names) != 2 {
+		t.Errorf("want 2 backups, get %v", names)
+	}
+}
+
+func TestFileRemoveOnlyBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	others := []string{"app.log.old", "app.log.bak.gz", "app.log.2026-10-18.txt", "app.log.1"}
+	for _, name := range others {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("other"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := newFileAppender(t, `type = File, path = "`+path+`", maxsize = 10, maxbackups = 1`)
+	defer f.Close()
+	for i := 0; i < 3; i++ {
+		f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "message"})
+	}
+
+	for _, name := range others {
+		if !exists(filepath.Join(dir, name)) {
+			t.Errorf("want %s not removed", name)
+		}
+	}
+	if n := len(backups(t, path)) - len(others); n != 1 {
+		t.Errorf("want 1 backup, get %d in %v", n, backups(t, path))
+	}
+}
+
+func TestFileRotateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := newFileAppender(t, `type = File, path = "`+path+`", maxsize = 10`)
+	defer f.Close()
+
+	f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "message"})
+	// the file is removed, so it can not be renamed by the rotation
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "failed"}); err == nil {
+		t.Fatal("want rotation error")
+	}
+
+	if err := f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "retried"}); err != nil {
+		t.Fatalf("want append after failed rotation, get %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "[I]retried\n" {
+		t.Errorf("want message in new file after retry, get %q", data)
+	}
+}
+
+func TestFileRotateDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := newFileAppender(t, `type = File, path = "`+path+`", rotate = daily, compress = true`)
+
+	day := time.Date(2026, 10, 18, 23, 59, 0, 0, time.Local)
+	f.now = func() time.Time { return day }
+	f.period = f.periodStart(day)
+
+	f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "day 1"})
+	day = day.Add(2 * time.Minute)
+	f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "day 2"})
+	f.Close()
+
+	if names := backups(t, path); len(names) != 1 || names[0] != "app.log.2026-10-18.gz" {
+		t.Fatalf("want backup app.log.2026-10-18.gz, get %v", names)
+	}
+
+	gzFile, err := os.Open(path + ".2026-10-18.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gzFile.Close()
+	r, err := gzip.NewReader(gzFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(r); string(data) != "[I]day 1\n" {
+		t.Errorf("want day 1 in backup, get %q", data)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "[I]day 2\n" {
+		t.Errorf("want day 2 in file, get %q", data)
+	}
+}
+
+func TestFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := newFileAppender(t, `type = File, path = "`+path+`"`)
+	defer f.Close()
+
+	f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "before"})
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("SIGHUP not supported: ", err)
+	}
+	for i := 0; i < 500; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f.Append(&common.LogMsg{Level: common.LevelInfo, Msg: "after"})
+	if data, _ := ioutil.ReadFile(path); string(data) != "[I]after\n" {
+		t.Errorf("want message after reopen in new file, get %q", data)
+	}
+	if data, _ := ioutil.ReadFile(path + ".1"); string(data) != "[I]before\n" {
+		t.Errorf("want message before reopen in moved file, get %q", data)
+	}
+}
+
+func TestFileConfigError(t *testing.T) {
+	for _, conf := range []string{`type = File`, `type = File, path = a.log, rotate = weekly`} {
+		c, _ := config.Read(strings.NewReader(conf))
+		if _, err := New(c); err == nil {
+			t.Errorf("want error of config %s", conf)
+		}
+	}
+}
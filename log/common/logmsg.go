@@ -10,4 +10,13 @@ type LogMsg struct {
 	Level int
 	Msg   string
 	Date  time.Time
+
+	// key value fields in logfmt, such as user=42 name="a b", shared by
+	// messages and never changed
+	Fields []byte
+
+	// set in detail mode only
+	Func      string // full name of the caller function, such as main.(*Server).Run
+	Goroutine int64  // id of the goroutine of the caller
+	Stack     string // stack trace of the caller, for Error and Fatal if enabled
 }
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"fmt"
+	. "github.com/tbud/x/log/common"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// With returns a child logger of l, which logs the key value pairs kv with
+// every message, such as With("user", id).Info("login ok"). The fields are
+// encoded once by With, not by every call of the child logger.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	if l == nil || len(kv) == 0 {
+		return l
+	}
+	return l.withFields(l.kvFields(kv))
+}
+
+func (l *Logger) withFields(fields []byte) *Logger {
+	child := *l
+	child.fields = fields
+	return &child
+}
+
+// kvFields returns the fields of l followed by the key value pairs kv.
+func (l *Logger) kvFields(kv []interface{}) []byte {
+	if len(kv) == 0 {
+		return l.fields
+	}
+	buf := make([]byte, len(l.fields), len(l.fields)+16*len(kv))
+	copy(buf, l.fields)
+	return appendFields(buf, kv)
+}
+
+func (l *Logger) FatalKV(msg string, kv ...interface{}) {
+	if l != nil && l.level >= LevelFatal {
+		l.output(LevelFatal, msg, l.kvFields(kv))
+	}
+
+	if l == nil {
+		fmt.Println(string(appendFields([]byte(msg+" "), kv)))
+	}
+}
+
+func (l *Logger) ErrorKV(msg string, kv ...interface{}) {
+	if l != nil && l.level >= LevelError {
+		l.output(LevelError, msg, l.kvFields(kv))
+	}
+
+	if l == nil {
+		fmt.Println(string(appendFields([]byte(msg+" "), kv)))
+	}
+}
+
+func (l *Logger) WarnKV(msg string, kv ...interface{}) {
+	if l != nil && l.level >= LevelWarn {
+		l.output(LevelWarn, msg, l.kvFields(kv))
+	}
+
+	if l == nil {
+		fmt.Println(string(appendFields([]byte(msg+" "), kv)))
+	}
+}
+
+func (l *Logger) InfoKV(msg string, kv ...interface{}) {
+	if l != nil && l.level >= LevelInfo {
+		l.output(LevelInfo, msg, l.kvFields(kv))
+	}
+}
+
+func (l *Logger) DebugKV(msg string, kv ...interface{}) {
+	if l != nil && l.level >= LevelDebug {
+		l.output(LevelDebug, msg, l.kvFields(kv))
+	}
+}
+
+func (l *Logger) TraceKV(msg string, kv ...interface{}) {
+	if l != nil && l.level >= LevelTrace {
+		l.output(LevelTrace, msg, l.kvFields(kv))
+	}
+}
+
+// appendFields appends the key value pairs kv to buf in logfmt, such as
+// user=42 name="a b". A key without value has the value !MISSING.
+func appendFields(buf []byte, kv []interface{}) []byte {
+	for i := 0; i < len(kv); i += 2 {
+		if len(buf) > 0 && buf[len(buf)-1] != ' ' {
+			buf = append(buf, ' ')
+		}
+
+		if key, ok := kv[i].(string); ok {
+			buf = appendFieldString(buf, key)
+		} else {
+			buf = appendFieldString(buf, fmt.Sprint(kv[i]))
+		}
+		buf = append(buf, '=')
+
+		if i+1 == len(kv) {
+			buf = append(buf, "!MISSING"...)
+		} else {
+			buf = appendFieldValue(buf, kv[i+1])
+		}
+	}
+	return buf
+}
+
+func appendFieldValue(buf []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendFieldString(buf, v)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case time.Duration:
+		return append(buf, v.String()...)
+	case time.Time:
+		return v.AppendFormat(buf, time.RFC3339Nano)
+	}
+
+	// the methods of a nil pointer, such as (*time.Time)(nil), may panic
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return append(buf, "null"...)
+	}
+
+	switch v := value.(type) {
+	case error:
+		return appendFieldString(buf, v.Error())
+	case fmt.Stringer:
+		return appendFieldString(buf, v.String())
+	}
+	return appendFieldString(buf, fmt.Sprint(value))
+}
+
+// appendFieldString appends s, quoted if it is empty or has spaces,
+// quotes, '=' or non printable characters.
+func appendFieldString(buf []byte, s string) []byte {
+	if needQuote(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func needQuote(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == utf8.RuneError || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -37,6 +37,12 @@ const (
 
 	patternMsg
 
+	patternFunc
+	patternGoroutine
+	patternStack
+
+	patternFields
+
 	patternString
 )
 
@@ -47,7 +53,7 @@ const (
 
 type patternSegment struct {
 	patternType int
-	segLen      int // save year, nanoSec len
+	segLen      int // save year, nanoSec len, 1 if fields are preceded by a space
 	seg         []byte
 }
 
@@ -74,6 +80,10 @@ var keyCharMap = map[int]int{
 	'f': patternShortFile,
 	'm': patternMsg,
 	'n': patternLine,
+	'M': patternFunc,      // detail mode only
+	't': patternGoroutine, // detail mode only
+	'S': patternStack,     // detail mode only, in new lines after the message
+	'X': patternFields,    // key value fields, after %m if not in the pattern
 }
 
 func stateKeyword(p *PatternLayout, c int) int {
@@ -137,6 +147,7 @@ func (p *PatternLayout) parse() error {
 			return p.err
 		}
 	}
+	p.insertFields()
 	p.segments = append(p.segments, patternSegment{patternType: patternString, seg: []byte("\n")})
 
 	for _, segment := range p.segments {
@@ -164,6 +175,23 @@ func (p *PatternLayout) parse() error {
 	return nil
 }
 
+// insertFields inserts the fields after the message if the pattern has no
+// %X, so the fields of messages are not lost by patterns such as "[%l]%m".
+func (p *PatternLayout) insertFields() {
+	for _, segment := range p.segments {
+		if segment.patternType == patternFields {
+			return
+		}
+	}
+	for i, segment := range p.segments {
+		if segment.patternType == patternMsg {
+			fields := patternSegment{patternType: patternFields, segLen: 1}
+			p.segments = append(p.segments[:i+1], append([]patternSegment{fields}, p.segments[i+1:]...)...)
+			return
+		}
+	}
+}
+
 func (p *PatternLayout) Format(buf *[]byte, m *LogMsg) error {
 	if p.needTime {
 		year, month, day := m.Date.Date()
@@ -219,6 +247,22 @@ func (p *PatternLayout) Format(buf *[]byte, m *LogMsg) error {
 			*buf = append(*buf, LogLevelToShortString(m.Level)...)
 		case patternMsg:
 			*buf = append(*buf, m.Msg...)
+		case patternFunc:
+			*buf = append(*buf, m.Func...)
+		case patternGoroutine:
+			itoa(buf, int(m.Goroutine), -1)
+		case patternStack:
+			if len(m.Stack) > 0 {
+				*buf = append(*buf, '\n')
+				*buf = append(*buf, m.Stack...)
+			}
+		case patternFields:
+			if len(m.Fields) > 0 {
+				if segment.segLen > 0 {
+					*buf = append(*buf, ' ')
+				}
+				*buf = append(*buf, m.Fields...)
+			}
 		case patternString:
 			*buf = append(*buf, segment.seg...)
 		}
@@ -255,7 +299,7 @@ func itoa(buf *[]byte, i int, wid int) {
 	*buf = append(*buf, b[bp:]...)
 }
 
-func patternLayout(conf config.Config) (lay Layout, err error) {
+func patternLayout(conf *config.Config) (lay Layout, err error) {
 	layout := &PatternLayout{}
 	layout.pattern = []byte(conf.StringDefault("pattern", "[%l]%m"))
 	err = layout.parse()
@@ -1,50 +1,69 @@
 package log
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/tbud/x/config"
 	"github.com/tbud/x/log/appender"
 	. "github.com/tbud/x/log/common"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 type Logger struct {
-	fastMode      bool
-	level         int
-	rootAppenders []appender.Appender
-	appenders     map[string]appender.Appender
-	needFile      bool
-	needTime      bool
+	name         string // empty for the root logger
+	fastMode     bool
+	stackTrace   bool // detail mode records the stack trace of Error and Fatal
+	level        int
+	appenderList []appender.Appender // appenders of the messages of the logger
+	appenders    map[string]appender.Appender
+	needFile     bool
+	needTime     bool
+	fields       []byte // fields encoded by With, never changed
+	tree         *loggerTree
 }
 
-func New(conf config.Config) (*Logger, error) {
+func New(conf *config.Config) (*Logger, error) {
 	logger := Logger{appenders: map[string]appender.Appender{}}
 
 	err := logger.loadAppenders(conf.SubConfig("appender"))
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = logger.initRoot(conf.SubConfig("root"))
+	}
+	if err == nil {
+		logger.tree = newLoggerTree(&logger)
+		err = logger.tree.load("", conf.SubConfig("loggers"))
 	}
-
-	err = logger.initRoot(conf.SubConfig("root"))
 	if err != nil {
+		// release the appenders which are created, such as files
+		logger.Close()
 		return nil, err
 	}
 
 	return &logger, nil
 }
 
+// SetLevel sets the level of l, and of the named loggers which inherit
+// it, see Named. A logger of With keeps the level it is created with.
 func (l *Logger) SetLevel(level int) {
-	if l != nil && level >= LevelFatal && level <= LevelTrace {
+	if l == nil || level < LevelFatal || level > LevelTrace {
+		return
+	}
+	if l.tree != nil {
+		l.tree.setLevel(l, level)
+	} else {
 		l.level = level
 	}
 }
 
 func (l *Logger) Fatal(format string, v ...interface{}) {
 	if l != nil && l.level >= LevelFatal {
-		l.output(LevelFatal, format, v...)
+		l.output(LevelFatal, fmt.Sprintf(format, v...), l.fields)
 	}
 
 	if l == nil {
@@ -54,7 +73,7 @@ func (l *Logger) Fatal(format string, v ...interface{}) {
 
 func (l *Logger) Error(format string, v ...interface{}) {
 	if l != nil && l.level >= LevelError {
-		l.output(LevelError, format, v...)
+		l.output(LevelError, fmt.Sprintf(format, v...), l.fields)
 	}
 
 	if l == nil {
@@ -64,7 +83,7 @@ func (l *Logger) Error(format string, v ...interface{}) {
 
 func (l *Logger) Warn(format string, v ...interface{}) {
 	if l != nil && l.level >= LevelWarn {
-		l.output(LevelWarn, format, v...)
+		l.output(LevelWarn, fmt.Sprintf(format, v...), l.fields)
 	}
 
 	if l == nil {
@@ -74,36 +93,42 @@ func (l *Logger) Warn(format string, v ...interface{}) {
 
 func (l *Logger) Info(format string, v ...interface{}) {
 	if l != nil && l.level >= LevelInfo {
-		l.output(LevelInfo, format, v...)
+		l.output(LevelInfo, fmt.Sprintf(format, v...), l.fields)
 	}
 }
 
 func (l *Logger) Debug(format string, v ...interface{}) {
 	if l != nil && l.level >= LevelDebug {
-		l.output(LevelDebug, format, v...)
+		l.output(LevelDebug, fmt.Sprintf(format, v...), l.fields)
 	}
 }
 
 func (l *Logger) Trace(format string, v ...interface{}) {
 	if l != nil && l.level >= LevelTrace {
-		l.output(LevelTrace, format, v...)
+		l.output(LevelTrace, fmt.Sprintf(format, v...), l.fields)
 	}
 }
 
-func (l *Logger) initRoot(conf config.Config) error {
+func (l *Logger) initRoot(conf *config.Config) error {
 	l.fastMode = conf.BoolDefault("fastmode", true)
+	l.stackTrace = conf.BoolDefault("stacktrace", false)
 	l.level = LogStringToLevel(conf.StringDefault("level", "info"))
 
 	appenderRefs := conf.StringsDefault("appendrefs", []string{"console"})
 	for _, appenderRef := range appenderRefs {
 		if appender, ok := l.appenders[appenderRef]; ok {
-			l.rootAppenders = append(l.rootAppenders, appender)
+			l.appenderList = append(l.appenderList, appender)
 		} else {
 			return errors.New("appender " + appenderRef + " not exist for root init.")
 		}
 	}
 
-	for _, appender := range l.rootAppenders {
+	l.setNeeds()
+	return nil
+}
+
+func (l *Logger) setNeeds() {
+	for _, appender := range l.appenderList {
 		if appender.NeedFile() {
 			l.needFile = true
 		}
@@ -111,10 +136,9 @@ func (l *Logger) initRoot(conf config.Config) error {
 			l.needTime = true
 		}
 	}
-	return nil
 }
 
-func (l *Logger) loadAppenders(conf config.Config) error {
+func (l *Logger) loadAppenders(conf *config.Config) error {
 	if conf == nil || conf.KeyLen() == 0 {
 		appender, err := appender.New(nil)
 		if err != nil {
@@ -123,7 +147,7 @@ func (l *Logger) loadAppenders(conf config.Config) error {
 
 		l.appenders["console"] = appender
 	} else {
-		return conf.EachSubConfig(func(key string, subConf config.Config) error {
+		err := conf.EachSubConfig(func(key string, subConf *config.Config) error {
 			appender, err := appender.New(subConf)
 			if err != nil {
 				return errors.New("Load appender " + key + " error: " + err.Error())
@@ -132,25 +156,206 @@ func (l *Logger) loadAppenders(conf config.Config) error {
 			l.appenders[key] = appender
 			return nil
 		})
+		if err != nil {
+			return err
+		}
+		return l.setAppendRefs()
+	}
+	return nil
+}
+
+// setAppendRefs sets the referenced appenders of appenders such as Async.
+func (l *Logger) setAppendRefs() error {
+	for _, key := range l.appenderKeys(true) {
+		refApp := l.appenders[key].(appender.RefAppender)
+		if err := l.checkAppendRefs(key, refApp, []string{key}); err != nil {
+			return err
+		}
+
+		apps := []appender.Appender{}
+		for _, ref := range refApp.AppendRefs() {
+			apps = append(apps, l.appenders[ref])
+		}
+		if err := refApp.SetAppendRefs(apps); err != nil {
+			return errors.New("Load appender " + key + " error: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func (l *Logger) checkAppendRefs(key string, refApp appender.RefAppender, path []string) error {
+	for _, ref := range refApp.AppendRefs() {
+		app, ok := l.appenders[ref]
+		if !ok {
+			return errors.New("appender " + ref + " not exist for appender " + key + ".")
+		}
+		for _, p := range path {
+			if p == ref {
+				return errors.New("appender " + key + " references itself: " + strings.Join(append(path, ref), " -> "))
+			}
+		}
+		if r, ok := app.(appender.RefAppender); ok {
+			if err := l.checkAppendRefs(key, r, append(path, ref)); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
-func (l *Logger) output(level int, format string, v ...interface{}) {
+// appenderKeys returns the sorted keys of the appenders which reference
+// other appenders when refs is true, else the keys of the others.
+func (l *Logger) appenderKeys(refs bool) []string {
+	keys := []string{}
+	for key, app := range l.appenders {
+		if _, ok := app.(appender.RefAppender); ok == refs {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// closeOrder returns the keys of the appenders, an appender is before the
+// appenders it references.
+func (l *Logger) closeOrder() []string {
+	keys := []string{}
+	visited := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		if r, ok := l.appenders[key].(appender.RefAppender); ok {
+			for _, ref := range r.AppendRefs() {
+				visit(ref)
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	all := append(l.appenderKeys(true), l.appenderKeys(false)...)
+	for _, key := range all {
+		visit(key)
+	}
+
+	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+	return keys
+}
+
+// Flush waits until the messages buffered by appenders such as Async
+// are appended.
+func (l *Logger) Flush() error {
+	if l == nil {
+		return nil
+	}
+
+	var err error
+	for _, key := range l.closeOrder() {
+		if f, ok := l.appenders[key].(appender.Flusher); ok {
+			if err1 := f.Flush(); err == nil {
+				err = err1
+			}
+		}
+	}
+	return err
+}
+
+// Close flushes and closes the appenders, such as the files of File
+// appenders. Appenders such as Async are closed before the appenders they
+// reference.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	var err error
+	for _, key := range l.closeOrder() {
+		if c, ok := l.appenders[key].(appender.Closer); ok {
+			if err1 := c.Close(); err == nil {
+				err = err1
+			}
+		}
+	}
+	return err
+}
+
+func (l *Logger) output(level int, message string, fields []byte) {
 	if l.fastMode {
-		msg := LogMsg{Level: level, Msg: fmt.Sprintf(format, v...)}
+		msg := LogMsg{Level: level, Msg: message, Fields: fields}
 		if l.needTime {
 			msg.Date = time.Now()
 		}
 		if l.needFile {
 			msg.File, msg.Line = pcFileLineMaps.getFileLine()
 		}
-		for i := range l.rootAppenders {
-			l.rootAppenders[i].Append(&msg)
+		for i := range l.appenderList {
+			l.appenderList[i].Append(&msg)
 		}
 	} else {
-		// TODO detail mode
+		msg := LogMsg{Level: level, Msg: message, Fields: fields, Date: time.Now()}
+		msg.Func, msg.File, msg.Line = callerFunc()
+		msg.Goroutine = goroutineID()
+		if l.stackTrace && level <= LevelError {
+			msg.Stack = callerStack()
+		}
+		for i := range l.appenderList {
+			l.appenderList[i].Append(&msg)
+		}
+	}
+}
+
+// callerFunc returns the function, file and line of the caller of the
+// Logger method which calls output.
+func callerFunc() (function, file string, line int) {
+	pc, file, line, ok := runtime.Caller(3)
+	if !ok {
+		return "???", "???", 0
+	}
+	if f := runtime.FuncForPC(pc); f != nil {
+		function = f.Name()
+	}
+	return
+}
+
+// callerStack returns the stack trace from the caller of the Logger method
+// which calls output, in the format of runtime/debug.Stack without the
+// goroutine header.
+func callerStack() string {
+	var pcs [64]uintptr
+	n := runtime.Callers(4, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	buf := []byte{}
+	for {
+		frame, more := frames.Next()
+		buf = append(buf, frame.Function...)
+		buf = append(buf, "()\n\t"...)
+		buf = append(buf, frame.File...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(frame.Line), 10)
+		if !more {
+			break
+		}
+		buf = append(buf, '\n')
+	}
+	return string(buf)
+}
+
+// goroutineID returns the id of the current goroutine, parsed from the
+// header of its stack trace, such as "goroutine 18 [running]:".
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
 	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
 }
 
 var pcFileLineMaps = pcFileLineMap{m: map[uintptr]fileLine{}}
@@ -1,9 +1,17 @@
 package log
 
 import (
+	"errors"
 	"github.com/tbud/x/config"
+	"github.com/tbud/x/log/appender"
+	. "github.com/tbud/x/log/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func BenchmarkRuntimeCallerTest(b *testing.B) {
@@ -62,3 +70,298 @@ func TestNilLog(t *testing.T) {
 	log.Debug("debug")
 	log.Trace("trace")
 }
+
+func TestAsyncFileAppender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	conf, err := config.Read(strings.NewReader(`
+root {
+	level = info
+	appendrefs = [async]
+}
+appender {
+	async {
+		type = Async
+		appendrefs = [file]
+	}
+	file {
+		type = File
+		path = "` + path + `"
+		layout.pattern = "[%l] %m"
+	}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		logger.Info("message %d", i)
+	}
+	if err = logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = logger.appenders["file"].Append(&LogMsg{Level: LevelInfo, Msg: "closed"}); err == nil {
+		t.Error("want file appender closed by the logger")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[I] message 0\n[I] message 1\n[I] message 2\n"; string(data) != want {
+		t.Errorf("want %q, get %q", want, data)
+	}
+}
+
+func TestAppendRefsError(t *testing.T) {
+	confs := []string{
+		"appender.a { type = Async\nappendrefs = [b] }",
+		"appender.a { type = Async\nappendrefs = [b] }\nappender.b { type = Async\nappendrefs = [a] }",
+	}
+	for _, c := range confs {
+		conf, err := config.Read(strings.NewReader(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = New(conf); err == nil {
+			t.Errorf("want error of appender refs %s", c)
+		}
+	}
+}
+
+func TestDetailMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	conf, err := config.Read(strings.NewReader(`
+root {
+	fastmode = false
+	stacktrace = true
+	appendrefs = [file]
+}
+appender.file {
+	type = File
+	path = "` + path + `"
+	layout.pattern = "[%l] %M %f:%n goroutine %t: %m%S"
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("info")
+	logger.Error("error")
+	logger.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 4 {
+		t.Fatalf("want info, error and its stack trace, get %s", data)
+	}
+	if !strings.HasPrefix(lines[0], "[I] github.com/tbud/x/log.TestDetailMode log_test.go:") || !strings.HasSuffix(lines[0], ": info") {
+		t.Errorf("want caller function and file of info, get %s", lines[0])
+	}
+	if strings.Contains(lines[0], "goroutine 0:") || !strings.Contains(lines[0], " goroutine ") {
+		t.Errorf("want goroutine id of info, get %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], ": error") || lines[2] != "github.com/tbud/x/log.TestDetailMode()" {
+		t.Errorf("want stack trace of error from caller, get %s", data)
+	}
+}
+
+func TestNamedLogger(t *testing.T) {
+	dir := t.TempDir()
+	conf, err := config.Read(strings.NewReader(`
+root {
+	level = warn
+	appendrefs = [root]
+}
+appender {
+	root { type = File, path = "` + filepath.Join(dir, "root.log") + `" }
+	db { type = File, path = "` + filepath.Join(dir, "db.log") + `" }
+}
+loggers {
+	db {
+		level = debug
+		appendrefs = [db]
+	}
+	"db.pool" {
+		level = trace
+	}
+	cache {
+		additivity = false
+		appendrefs = [db]
+		redis.level = info
+	}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if logger.Named("db").Named("pool") != logger.Named("db.pool") {
+		t.Error("want the same logger of db.pool")
+	}
+	if name := logger.Named("db").Named("pool").Name(); name != "db.pool" {
+		t.Errorf("want name db.pool, get %s", name)
+	}
+
+	logger.Info("root info")
+	logger.Warn("root warn")
+	logger.Named("db").Debug("db debug")
+	logger.Named("db").Trace("db trace")
+	logger.Named("db.pool.conn").Trace("conn trace")
+	logger.Named("cache.redis").Debug("redis debug")
+	logger.Named("cache.redis").Info("redis info")
+	logger.Named("http").Info("http info")
+	logger.Named("http").Warn("http warn")
+	logger.Close()
+
+	tests := []struct {
+		file, want string
+	}{
+		{"root.log", "[W]root warn\n[D]db debug\n[T]conn trace\n[W]http warn\n"},
+		{"db.log", "[D]db debug\n[T]conn trace\n[I]redis info\n"},
+	}
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(dir, test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.want {
+			t.Errorf("want %s %q, get %q", test.file, test.want, data)
+		}
+	}
+
+	conf, _ = config.Read(strings.NewReader("loggers.db.appendrefs = [file]"))
+	if _, err = New(conf); err == nil {
+		t.Error("want error of not exist appender")
+	}
+}
+
+func TestNamedLoggerSetLevel(t *testing.T) {
+	conf, err := config.Read(strings.NewReader(`
+root.level = warn
+loggers.db.level = error
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	http, db, pool := logger.Named("http"), logger.Named("db"), logger.Named("db.pool")
+	logger.SetLevel(LevelDebug)
+	if http.level != LevelDebug || db.level != LevelError {
+		t.Errorf("want levels of http debug and db error after root SetLevel, get %d, %d", http.level, db.level)
+	}
+
+	db.SetLevel(LevelTrace)
+	if pool.level != LevelTrace || logger.Named("db.conn").level != LevelTrace || http.level != LevelDebug {
+		t.Errorf("want level of db.pool trace after db SetLevel, get %d", pool.level)
+	}
+
+	with := http.With("k", "v")
+	with.SetLevel(LevelError)
+	if with.level != LevelError || http.level != LevelDebug {
+		t.Errorf("want level of With logger only, get %d, %d", with.level, http.level)
+	}
+}
+
+func TestFields(t *testing.T) {
+	dir := t.TempDir()
+	conf, err := config.Read(strings.NewReader(`
+root.appendrefs = [plain, fields]
+appender {
+	plain { type = File, path = "` + filepath.Join(dir, "plain.log") + `" }
+	fields {
+		type = File
+		path = "` + filepath.Join(dir, "fields.log") + `"
+		layout.pattern = "%X | %m"
+	}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := logger.With("user", 42, "name", "a b")
+	user.Info("login ok")
+	user.InfoKV("query", "rows", 3, "err", errors.New("bad=1"), "cached")
+	user.Named("db").Warn("slow %dms", 80)
+	logger.InfoKV("start", "ok", true)
+	logger.Info("plain")
+	logger.Close()
+
+	tests := []struct {
+		file, want string
+	}{
+		{"plain.log", `[I]login ok user=42 name="a b"
+[I]query user=42 name="a b" rows=3 err="bad=1" cached=!MISSING
+[W]slow 80ms user=42 name="a b"
+[I]start ok=true
+[I]plain
+`},
+		{"fields.log", `user=42 name="a b" | login ok
+user=42 name="a b" rows=3 err="bad=1" cached=!MISSING | query
+user=42 name="a b" | slow 80ms
+ok=true | start
+ | plain
+`},
+	}
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(dir, test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.want {
+			t.Errorf("want %s %q, get %q", test.file, test.want, data)
+		}
+	}
+}
+
+func TestFieldNilPointer(t *testing.T) {
+	var (
+		date *time.Time
+		err  *os.PathError
+	)
+	buf := appendFields(nil, []interface{}{"date", date, "err", err, "ptr", (*int)(nil)})
+	if want := "date=null err=null ptr=null"; string(buf) != want {
+		t.Errorf("want %q, get %q", want, buf)
+	}
+}
+
+type discardAppender struct{}
+
+func (discardAppender) Append(m *LogMsg) error { return nil }
+func (discardAppender) NeedFile() bool         { return false }
+func (discardAppender) NeedTime() bool         { return false }
+
+func TestWithNoReencode(t *testing.T) {
+	logger := &Logger{fastMode: true, level: LevelInfo, appenderList: []appender.Appender{discardAppender{}}}
+	child := logger.With("user", 42, "name", "a b")
+
+	plain := testing.AllocsPerRun(100, func() { logger.Info("login ok") })
+	with := testing.AllocsPerRun(100, func() { child.Info("login ok") })
+	if with > plain {
+		t.Errorf("want no allocation of bound fields, get %v allocs, %v without fields", with, plain)
+	}
+}
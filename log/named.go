@@ -0,0 +1,192 @@
+package log
+
+import (
+	"errors"
+	"github.com/tbud/x/config"
+	"github.com/tbud/x/log/appender"
+	. "github.com/tbud/x/log/common"
+	"strings"
+	"sync"
+)
+
+// A loggerConfig is the config of a named logger in the loggers block:
+//
+//	loggers {
+//		db {
+//			level = debug
+//			appendrefs = [file]
+//			additivity = false
+//		}
+//		"db.pool" {
+//			level = trace
+//		}
+//	}
+type loggerConfig struct {
+	level      int
+	hasLevel   bool
+	appenders  []appender.Appender
+	additivity bool // the messages are appended to the appenders of the ancestors too
+}
+
+var loggerOptions = map[string]bool{"level": true, "appendrefs": true, "additivity": true}
+
+// A loggerTree holds the configs of the named loggers, and the loggers
+// returned by Named.
+type loggerTree struct {
+	sync.Mutex
+	root    *Logger
+	configs map[string]*loggerConfig
+	loggers map[string]*Logger
+}
+
+func newLoggerTree(root *Logger) *loggerTree {
+	return &loggerTree{
+		root:    root,
+		configs: map[string]*loggerConfig{},
+		loggers: map[string]*Logger{},
+	}
+}
+
+// load loads the loggers of conf, named with prefix. The keys of a logger
+// other than its options are child loggers, such as db { pool { ... } }.
+func (t *loggerTree) load(prefix string, conf *config.Config) error {
+	if conf == nil {
+		return nil
+	}
+	return conf.EachSubConfig(func(key string, sub *config.Config) error {
+		name := key
+		if len(prefix) > 0 {
+			if loggerOptions[key] {
+				return nil
+			}
+			name = prefix + "." + key
+		}
+		if sub == nil {
+			return errors.New("logger " + name + " config must be an object.")
+		}
+
+		lc := &loggerConfig{additivity: sub.BoolDefault("additivity", true)}
+		if level, ok := sub.String("level"); ok {
+			lc.level = LogStringToLevel(level)
+			lc.hasLevel = true
+		}
+		for _, appenderRef := range sub.StringsDefault("appendrefs", nil) {
+			appender, ok := t.root.appenders[appenderRef]
+			if !ok {
+				return errors.New("appender " + appenderRef + " not exist for logger " + name + ".")
+			}
+			lc.appenders = append(lc.appenders, appender)
+		}
+		t.configs[name] = lc
+		return t.load(name, sub)
+	})
+}
+
+// logger returns the logger of name, which is created by the configs of
+// name and its ancestors.
+func (t *loggerTree) logger(name string) *Logger {
+	t.Lock()
+	defer t.Unlock()
+
+	if logger, ok := t.loggers[name]; ok {
+		return logger
+	}
+
+	root := t.root
+	logger := &Logger{
+		name:       name,
+		fastMode:   root.fastMode,
+		stackTrace: root.stackTrace,
+		level:      t.level(name),
+		appenders:  root.appenders,
+		tree:       t,
+	}
+
+	additive := true
+	for n := name; len(n) > 0 && additive; n = parentName(n) {
+		if lc, ok := t.configs[n]; ok {
+			logger.appenderList = append(logger.appenderList, lc.appenders...)
+			additive = lc.additivity
+		}
+	}
+	if additive {
+		logger.appenderList = append(logger.appenderList, root.appenderList...)
+	}
+
+	logger.setNeeds()
+	t.loggers[name] = logger
+	return logger
+}
+
+// level returns the level of the nearest ancestor of name with a level,
+// or the level of root.
+func (t *loggerTree) level(name string) int {
+	for n := name; len(n) > 0; n = parentName(n) {
+		if lc, ok := t.configs[n]; ok && lc.hasLevel {
+			return lc.level
+		}
+	}
+	return t.root.level
+}
+
+// setLevel sets the level of l, and of the loggers which inherit it.
+func (t *loggerTree) setLevel(l *Logger, level int) {
+	t.Lock()
+	defer t.Unlock()
+
+	switch {
+	case l == t.root:
+		l.level = level
+	case l == t.loggers[l.name]:
+		lc, ok := t.configs[l.name]
+		if !ok {
+			lc = &loggerConfig{additivity: true}
+			t.configs[l.name] = lc
+		}
+		lc.level, lc.hasLevel = level, true
+	default:
+		// a logger of With is not in the tree
+		l.level = level
+		return
+	}
+
+	for name, logger := range t.loggers {
+		logger.level = t.level(name)
+	}
+}
+
+func parentName(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// Named returns the child logger name of l, such as Named("db.pool") of
+// the root logger, which is Named("pool") of the logger db. The level of
+// the logger is the level of the nearest ancestor configured in the
+// loggers block or by SetLevel, or the level of root, also when SetLevel
+// is called after Named. Messages are appended to the
+// appenders of the configured loggers up to root, until a logger with
+// additivity = false. Loggers are created once, so Named returns the same
+// logger for a name, unless l has fields of With, which the child keeps.
+func (l *Logger) Named(name string) *Logger {
+	if l == nil || len(name) == 0 {
+		return l
+	}
+	if len(l.name) > 0 {
+		name = l.name + "." + name
+	}
+	if len(l.fields) > 0 {
+		return l.tree.logger(name).withFields(l.fields)
+	}
+	return l.tree.logger(name)
+}
+
+// Name returns the name of l, empty for the root logger.
+func (l *Logger) Name() string {
+	if l == nil {
+		return ""
+	}
+	return l.name
+}
@@ -1,8 +1,10 @@
 package meta
 
 import (
+	"errors"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"unicode"
@@ -14,6 +16,9 @@ type MetaInfo struct {
 	Tagged        bool
 	Min           int
 	Max           int
+	HasMin        bool // Min is set by min=n
+	HasMax        bool // Max is set by max=n
+	Required      bool
 	Skip          bool
 	OmitEmpty     bool
 	Quote         bool
@@ -50,7 +55,7 @@ type metaCache struct {
 	m map[reflect.Type][]MetaInfo
 }
 
-func (m metaCache) getOrElse(key reflect.Type, f func() []MetaInfo) []MetaInfo {
+func (m *metaCache) getOrElse(key reflect.Type, f func() []MetaInfo) []MetaInfo {
 	m.RLock()
 	v, ok := m.m[key]
 	m.RUnlock()
@@ -77,11 +82,12 @@ func (m metaCache) getOrElse(key reflect.Type, f func() []MetaInfo) []MetaInfo {
 	return v
 }
 
-var metaCaches = map[string]metaCache{
-	metaTag:     metaCache{},
-	jsonTag:     metaCache{},
-	ormTag:      metaCache{},
-	validateTag: metaCache{},
+var metaCaches = map[string]*metaCache{
+	metaTag:     &metaCache{},
+	hoconTag:    &metaCache{},
+	jsonTag:     &metaCache{},
+	ormTag:      &metaCache{},
+	validateTag: &metaCache{},
 }
 
 func originName(name string) string {
@@ -134,6 +140,8 @@ func meta(t reflect.Type, tagName string) (retMeta []MetaInfo, err error) {
 			metaFromTag(t, metaTag, metaInfos)
 			return metaInfos
 		})
+		// copy, the meta tag infos are shared by all tags
+		ms = append([]MetaInfo{}, ms...)
 
 		metaFromTag(t, tagName, ms)
 
@@ -164,6 +172,10 @@ func metaFromTag(t reflect.Type, tagName string, metaInfos []MetaInfo) {
 		meta := &metaInfos[i]
 
 		nameSeted := false
+		regExpSeted := false
+		// the options of validation are only in the validate tag, other
+		// tags such as json or hocon may have a field named required
+		validation := tagName == validateTag
 		if len(tag) > 0 {
 			meta.Tagged = true
 			for _, v := range strings.Split(tag, ",") {
@@ -176,11 +188,44 @@ func metaFromTag(t reflect.Type, tagName string, metaInfos []MetaInfo) {
 					meta.OmitEmpty = true
 				case v == "string" || v == "%q":
 					meta.Quote = true
+				case validation && v == "required":
+					meta.Required = true
+				case validation && strings.HasPrefix(v, "min="):
+					meta.Min = tagInt(field, v)
+					meta.HasMin = true
+				case validation && strings.HasPrefix(v, "max="):
+					meta.Max = tagInt(field, v)
+					meta.HasMax = true
+				case validation && strings.HasPrefix(v, "regexp="):
+					meta.MatchRegExp = v[len("regexp="):]
+				case regExpSeted:
+					// the regexp has a comma, such as ^[a-z]{1,3}$
+					meta.MatchRegExp += "," + v
 				case !nameSeted:
 					meta.Name = v
 					nameSeted = true
 				}
+				if validation && isTagOption(v) {
+					regExpSeted = strings.HasPrefix(v, "regexp=")
+				}
 			}
 		}
 	}
 }
+
+// isTagOption reports whether v is an option of tag, but not a name.
+func isTagOption(v string) bool {
+	switch v {
+	case "-", "~", "omitempty", "string", "%q", "required":
+		return true
+	}
+	return strings.HasPrefix(v, "min=") || strings.HasPrefix(v, "max=") || strings.HasPrefix(v, "regexp=")
+}
+
+func tagInt(field reflect.StructField, v string) int {
+	i, err := strconv.Atoi(v[strings.Index(v, "=")+1:])
+	if err != nil {
+		panic(errors.New("meta: invalid " + v + " in tag of field " + field.Name))
+	}
+	return i
+}
@@ -50,3 +50,61 @@ func TestOrmMeta(t *testing.T) {
 		}
 	}
 }
+
+type ValidateTest struct {
+	Port  int    `validate:"min=1,max=65535,required"`
+	Name  string `validate:"name,regexp=^[a-z]{1,3}$,required"`
+	Empty string
+}
+
+func TestValidateMeta(t *testing.T) {
+	mi, err := ValidateMeta(reflect.TypeOf(ValidateTest{}))
+	if err != nil {
+		t.Fatalf("test validate meta get a error: %v", err)
+	}
+
+	want := []MetaInfo{
+		MetaInfo{Name: "Port", OriginName: "Port", Tagged: true, Min: 1, Max: 65535, HasMin: true, HasMax: true, Required: true},
+		MetaInfo{Name: "name", Tagged: true, MatchRegExp: "^[a-z]{1,3}$", Required: true},
+		MetaInfo{Name: "Empty", OriginName: "Empty"},
+	}
+
+	for i := 0; i < len(mi); i++ {
+		if r, w := mi[i], want[i]; r != w {
+			t.Errorf("want %v, get %v", w, r)
+		}
+	}
+
+	type badTag struct {
+		Port int `validate:"min=a"`
+	}
+	if _, err := ValidateMeta(reflect.TypeOf(badTag{})); err == nil {
+		t.Error("want error of invalid min")
+	}
+}
+
+func TestValidateOptionsOnlyInValidateTag(t *testing.T) {
+	type field struct {
+		Required string `json:"required" hocon:"required" validate:"required"`
+		Limit    int    `json:"min=1,omitempty" validate:"min=1"`
+	}
+
+	mi, err := JsonMeta(reflect.TypeOf(field{}))
+	if err != nil {
+		t.Fatalf("test json meta get a error: %v", err)
+	}
+	if mi[0].Name != "required" || mi[0].Required {
+		t.Errorf("want json field named required, get %v", mi[0])
+	}
+	if mi[1].Name != "min=1" || mi[1].HasMin || !mi[1].OmitEmpty {
+		t.Errorf("want json field named min=1, get %v", mi[1])
+	}
+
+	if mi, err = HoconMeta(reflect.TypeOf(field{})); err != nil || mi[0].Name != "required" || mi[0].Required {
+		t.Errorf("want hocon field named required, get %v, %v", mi, err)
+	}
+
+	if mi, err = ValidateMeta(reflect.TypeOf(field{})); err != nil || !mi[0].Required || !mi[1].HasMin {
+		t.Errorf("want validate options, get %v, %v", mi, err)
+	}
+}
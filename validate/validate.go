@@ -0,0 +1,245 @@
+// Package validate checks the fields of structs by the validate tag,
+// such as `validate:"min=1,max=65535,regexp=^[a-z]+$,required"`.
+//
+// The options of the tag are:
+//
+//	required    the field must not be the zero value
+//	min=n       numbers must be >= n, strings, slices and maps must have at least n elements
+//	max=n       numbers must be <= n, strings, slices and maps must have at most n elements
+//	regexp=re   strings must match re, re may contain commas
+//
+// Nested structs, pointers, and the elements of slices, arrays and maps are
+// validated too. It is used to check a struct after config.Unmarshal or
+// json.Unmarshal:
+//
+//	if err := config.Unmarshal(conf, &server); err != nil {
+//		return err
+//	}
+//	if err := validate.Struct(&server); err != nil {
+//		return err
+//	}
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tbud/x/meta"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A FieldError describes a field that violates its validate tag.
+type FieldError struct {
+	Field string      // path of the field, such as Servers[0].Port
+	Value interface{} // value of the field
+	Rule  string      // violated rule, such as min=1
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.message()
+}
+
+func (e *FieldError) message() string {
+	switch {
+	case e.Rule == "required":
+		return "required"
+	case strings.HasPrefix(e.Rule, "regexp="):
+		return fmt.Sprintf("%q does not match %s", e.Value, e.Rule[len("regexp="):])
+	}
+	return fmt.Sprintf("%v violates %s", e.Value, e.Rule)
+}
+
+// Errors is the error of all violating fields of a struct.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "validate: " + strings.Join(msgs, "; ")
+}
+
+// Struct validates the struct v or the struct pointed to by v. It returns
+// Errors with every violating field, or nil if all fields are valid.
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("validate: Struct(" + fmt.Sprintf("%T", v) + "), v must be a struct or a pointer to struct")
+	}
+
+	var errs Errors
+	if err := validateStruct("", rv, &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func joinField(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+func validateStruct(path string, rv reflect.Value, errs *Errors) error {
+	metas, err := meta.ValidateMeta(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for i, m := range metas {
+		sf := rv.Type().Field(i)
+		if m.Skip || len(sf.PkgPath) > 0 && !sf.Anonymous {
+			continue
+		}
+
+		field := rv.Field(i)
+		fieldPath := joinField(path, m.Name)
+		// embedded struct without name, fields are promoted
+		if len(m.Name) == 0 {
+			fieldPath = path
+		}
+
+		if err := validateField(fieldPath, field, m, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateField(path string, rv reflect.Value, m meta.MetaInfo, errs *Errors) error {
+	if m.Required && rv.IsZero() {
+		*errs = append(*errs, &FieldError{path, rv.Interface(), "required"})
+		return nil
+	}
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	var size float64
+	hasSize := true
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		size = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = rv.Float()
+	case reflect.String:
+		size = float64(utf8.RuneCountInString(rv.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size = float64(rv.Len())
+	default:
+		hasSize = false
+	}
+
+	if hasSize && m.HasMin && size < float64(m.Min) {
+		*errs = append(*errs, &FieldError{path, rv.Interface(), "min=" + strconv.Itoa(m.Min)})
+	}
+	if hasSize && m.HasMax && size > float64(m.Max) {
+		*errs = append(*errs, &FieldError{path, rv.Interface(), "max=" + strconv.Itoa(m.Max)})
+	}
+
+	if len(m.MatchRegExp) > 0 && rv.Kind() == reflect.String {
+		re, err := compile(m.MatchRegExp)
+		if err != nil {
+			return errors.New("validate: invalid regexp of field " + path + ": " + err.Error())
+		}
+		if !re.MatchString(rv.String()) {
+			*errs = append(*errs, &FieldError{path, rv.String(), "regexp=" + m.MatchRegExp})
+		}
+	}
+
+	return validateElems(path, rv, errs)
+}
+
+// validateElems validates the structs in rv.
+func validateElems(path string, rv reflect.Value, errs *Errors) error {
+	switch rv.Kind() {
+	case reflect.Struct:
+		return validateStruct(path, rv, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := validateElem(path+"["+strconv.Itoa(i)+"]", rv.Index(i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// the keys are sorted, so the errors are in the same order
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = fmt.Sprint(key.Interface())
+		}
+		sort.Sort(byName{keys, names})
+		for i, key := range keys {
+			if err := validateElem(path+"["+names[i]+"]", rv.MapIndex(key), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// byName sorts the keys of a map by their names.
+type byName struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (b byName) Len() int           { return len(b.keys) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+func (b byName) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+}
+
+func validateElem(path string, rv reflect.Value, errs *Errors) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	return validateElems(path, rv, errs)
+}
+
+var regexps = struct {
+	sync.RWMutex
+	m map[string]*regexp.Regexp
+}{m: map[string]*regexp.Regexp{}}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	regexps.RLock()
+	re, ok := regexps.m[expr]
+	regexps.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	regexps.Lock()
+	regexps.m[expr] = re
+	regexps.Unlock()
+	return re, nil
+}
@@ -0,0 +1,92 @@
+package validate
+
+import (
+	"github.com/tbud/x/config"
+	"strings"
+	"testing"
+)
+
+type Listen struct {
+	Host string `validate:"required,regexp=^[a-z][a-z0-9.]{0,62}$"`
+	Port int    `validate:"min=1,max=65535"`
+}
+
+type Server struct {
+	Name    string   `validate:"regexp=^[a-z]+$,required"`
+	Listens []Listen `validate:"min=1"`
+	Admin   *Listen
+	Tags    map[string]Listen
+	Workers uint `validate:"max=64"`
+	skip    int  `validate:"min=1"`
+}
+
+func TestStruct(t *testing.T) {
+	s := Server{
+		Name:    "web",
+		Listens: []Listen{{"localhost", 80}},
+		Workers: 8,
+	}
+	if err := Struct(&s); err != nil {
+		t.Errorf("valid struct get error: %v", err)
+	}
+
+	s = Server{
+		Name:    "Web",
+		Listens: []Listen{{"localhost", 0}, {"", 70000}},
+		Admin:   &Listen{"Admin", 8080},
+		Tags:    map[string]Listen{"c": {"c", -1}, "a": {"a", -1}, "b": {"b", 0}},
+		Workers: 100,
+	}
+	err := Struct(s)
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("want Errors get %v", err)
+	}
+
+	want := []string{
+		`Name: "Web" does not match ^[a-z]+$`,
+		"Listens[0].Port: 0 violates min=1",
+		"Listens[1].Host: required",
+		"Listens[1].Port: 70000 violates max=65535",
+		`Admin.Host: "Admin" does not match ^[a-z][a-z0-9.]{0,62}$`,
+		"Tags[a].Port: -1 violates min=1",
+		"Tags[b].Port: 0 violates min=1",
+		"Tags[c].Port: -1 violates min=1",
+		"Workers: 100 violates max=64",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("want %d errors get %v", len(want), err)
+	}
+	for i, w := range want {
+		if get := errs[i].Error(); get != w {
+			t.Errorf("error %d want %s get %s", i, w, get)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "validate: Name: ") {
+		t.Errorf("error message get %s", err.Error())
+	}
+
+	if err := Struct(1); err == nil || err.Error() != "validate: Struct(int), v must be a struct or a pointer to struct" {
+		t.Errorf("validate int get %v", err)
+	}
+}
+
+type Limits struct {
+	Size    string `validate:"min=2,max=3"`
+	Missing string `validate:"required"`
+}
+
+func TestStructAfterUnmarshal(t *testing.T) {
+	conf, err := config.Read(strings.NewReader("size = 中文字符\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l Limits
+	if err = config.Unmarshal(conf, &l); err != nil {
+		t.Fatal(err)
+	}
+	if err, want := Struct(&l), `validate: Size: 中文字符 violates max=3; Missing: required`; err == nil || err.Error() != want {
+		t.Errorf("want %s get %v", want, err)
+	}
+}